@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// dedup keeps one file of each duplicate group and replaces the others as
+// chosen by the action. Without -apply it only previews the changes.
+func dedup(args []string) {
+	options := parseDedupOptions(args)
+
+	store = openExistingStore(options.db)
+	defer store.Close()
+
+	groups, err := duplicates(func(entry FileEntry) bool {
+		return entry.Size >= int64(options.minSize) && (len(options.prefixes) == 0 || below(options.prefixes, entry.ID))
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	files, freed, failed := 0, int64(0), 0
+	for _, group := range groups {
+		entries := []FileEntry{}
+		for _, file := range group.Files {
+			var entry FileEntry
+			if err := store.Get(file, &entry); err == nil {
+				// entries of a database not scanned since its upgrade lack
+				// the hash of their group
+				entry.Hash, entry.Strong = group.Hash, group.Strong
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) < 2 {
+			continue
+		}
+		keepFirst(options.keep, options.prefer, entries)
+		if !keepRegular(entries) {
+			log.Printf("skip group %s: no regular file to keep", group.Hash)
+			continue
+		}
+
+		keep := entries[0]
+		fmt.Fprintf(out, "%-8s %s\n", "keep", keep.ID)
+		for _, entry := range entries[1:] {
+			// replacing or deleting a link to the kept file loses the content
+			if sameFile(keep.ID, entry.ID) {
+				fmt.Fprintf(out, "%-8s %s\n", "linked", entry.ID)
+				continue
+			}
+			fmt.Fprintf(out, "%-8s %s\n", options.action, entry.ID)
+			if options.apply {
+				out.Flush()
+				if err := deduplicate(options.action, keep, entry); err != nil {
+					log.Printf("%s '%s': %v", options.action, entry.ID, err)
+					failed++
+					continue
+				}
+			}
+			files++
+			freed += entry.Size
+		}
+		fmt.Fprintln(out)
+	}
+
+	if options.apply {
+		fmt.Fprintf(out, "%d files replaced, %s freed, %d failed\n", files, humanSize(freed), failed)
+	} else {
+		fmt.Fprintf(out, "%d files would be replaced, %s freed, run with -apply to change them\n", files, humanSize(freed))
+	}
+	out.Flush()
+
+	if failed > 0 {
+		store.Close()
+		os.Exit(1)
+	}
+}
+
+// keepFirst sorts the file to keep to the front. Ties of a policy keep the
+// shortest path.
+func keepFirst(policy keepPolicy, prefer []string, entries []FileEntry) {
+	rank := func(path string) int {
+		for i, dir := range prefer {
+			if below([]string{dir}, path) {
+				return i
+			}
+		}
+		return len(prefer)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case policy == keepOldest && !a.ModTime.Equal(b.ModTime):
+			return a.ModTime.Before(b.ModTime)
+		case policy == keepNewest && !a.ModTime.Equal(b.ModTime):
+			return a.ModTime.After(b.ModTime)
+		case policy == keepPreferred && rank(a.ID) != rank(b.ID):
+			return rank(a.ID) < rank(b.ID)
+		case len(a.ID) != len(b.ID):
+			return len(a.ID) < len(b.ID)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// keepRegular moves the first file which is no symbolic link to the front, a
+// link may point to one of the copies replaced. It reports false if there is
+// none.
+func keepRegular(entries []FileEntry) bool {
+	for i, entry := range entries {
+		info, err := os.Lstat(entry.ID)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		copy(entries[1:i+1], entries[:i])
+		entries[0] = entry
+		return true
+	}
+	return false
+}
+
+// sameFile reports whether a and b resolve to the same file.
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// deduplicate verifies that entry still has the content of keep and replaces
+// it by the action. The database follows the change.
+func deduplicate(action dedupAction, keep, entry FileEntry) error {
+	same, err := sameContent(keep.ID, entry.ID)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("content differs from '%s'", keep.ID)
+	}
+
+	switch action {
+	case actionDelete:
+		err = os.Remove(entry.ID)
+	case actionHardlink:
+		err = replace(entry.ID, func(tmp string) error {
+			return os.Link(keep.ID, tmp)
+		})
+	case actionSymlink:
+		err = replace(entry.ID, func(tmp string) error {
+			return os.Symlink(keep.ID, tmp)
+		})
+	case actionReflink:
+		err = replace(entry.ID, func(tmp string) error {
+			info, err := os.Stat(entry.ID)
+			if err != nil {
+				return err
+			}
+			return reflink(keep.ID, tmp, info.Mode().Perm())
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if action == actionDelete || action == actionSymlink {
+		// a symbolic link is no regular file of the index anymore
+		unlink(entry)
+		return store.Delete(entry.ID, FileEntry{})
+	}
+	info, err := os.Stat(entry.ID)
+	if err != nil {
+		return err
+	}
+	updated := newFileEntry(entry.ID, info)
+	updated.Partial, updated.Hash, updated.Strong = entry.Partial, entry.Hash, entry.Strong
+	return store.Upsert(entry.ID, updated)
+}
+
+// replace creates a temporary file next to path with link and renames it
+// over path, so path is never missing. The temporary name is unique and link
+// must not replace an existing file, so no other file is ever removed.
+func replace(path string, link func(tmp string) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.dedup")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	file.Close()
+	// the name is reserved, link creates the file again
+	if err := os.Remove(tmp); err != nil {
+		return err
+	}
+	if err := link(tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// sameContent compares two files byte for byte.
+func sameContent(a, b string) (bool, error) {
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	bufferA, bufferB := make([]byte, 64<<10), make([]byte, 64<<10)
+	for {
+		nA, errA := io.ReadFull(fileA, bufferA)
+		nB, errB := io.ReadFull(fileB, bufferB)
+		if !bytes.Equal(bufferA[:nA], bufferB[:nB]) {
+			return false, nil
+		}
+		endA, endB := isEOF(errA), isEOF(errB)
+		if errA != nil && !endA {
+			return false, errA
+		}
+		if errB != nil && !endB {
+			return false, errB
+		}
+		if endA || endB {
+			return endA && endB, nil
+		}
+	}
+}
+
+func isEOF(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
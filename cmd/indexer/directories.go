@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// dirNode is a directory of the indexed files. Its hash is a Merkle hash over
+// the names and hashes of its children, so equal hashes mean equal trees.
+type dirNode struct {
+	path     string
+	parent   *dirNode
+	children []*dirNode
+	files    map[string]dirFile
+
+	hash  string
+	size  int64
+	count int
+	// contents counts the files below the directory by content key, only
+	// for content which exists more than once
+	contents map[string]int
+}
+
+// considered reports whether the directory holds content of its own. A
+// directory with a single subdirectory and no files, like the parents of the
+// scan roots, only repeats its child.
+func (d *dirNode) considered() bool {
+	return d.size > 0 && (len(d.files) > 0 || len(d.children) > 1)
+}
+
+func (d *dirNode) below(ancestor *dirNode) bool {
+	for p := d.parent; p != nil; p = p.parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+type dirFile struct {
+	key  string
+	size int64
+}
+
+type dirTree struct {
+	dirs map[string]*dirNode
+	// sizes holds the size of the content existing more than once
+	sizes map[string]int64
+}
+
+// contentKey identifies the content of a file. Files without a full hash have
+// no copy, their content is unique.
+func contentKey(entry FileEntry) string {
+	if entry.Hash == "" {
+		return "~" + entry.ID
+	}
+	return entry.Hash + entry.Strong
+}
+
+func newDirTree(entries []FileEntry) *dirTree {
+	t := &dirTree{dirs: map[string]*dirNode{}, sizes: map[string]int64{}}
+	copies := map[string]int{}
+	for _, entry := range entries {
+		key := contentKey(entry)
+		t.sizes[key] = entry.Size
+		copies[key]++
+		dir := t.dir(filepath.Dir(entry.ID))
+		dir.files[filepath.Base(entry.ID)] = dirFile{key: key, size: entry.Size}
+	}
+	for key, n := range copies {
+		if n < 2 {
+			delete(t.sizes, key)
+		}
+	}
+	for _, d := range t.dirs {
+		if d.parent == nil {
+			t.summarize(d)
+		}
+	}
+	return t
+}
+
+func (t *dirTree) dir(path string) *dirNode {
+	if d, ok := t.dirs[path]; ok {
+		return d
+	}
+	d := &dirNode{path: path, files: map[string]dirFile{}, contents: map[string]int{}}
+	t.dirs[path] = d
+	if parent := filepath.Dir(path); parent != path {
+		d.parent = t.dir(parent)
+		d.parent.children = append(d.parent.children, d)
+	}
+	return d
+}
+
+// summarize computes hash, size, count and contents bottom up.
+func (t *dirTree) summarize(d *dirNode) {
+	entries := []string{}
+	for name, file := range d.files {
+		entries = append(entries, "f\x00"+name+"\x00"+file.key)
+		if _, ok := t.sizes[file.key]; ok {
+			d.contents[file.key]++
+		}
+		d.size += file.size
+		d.count++
+	}
+	for _, child := range d.children {
+		t.summarize(child)
+		entries = append(entries, "d\x00"+filepath.Base(child.path)+"\x00"+child.hash)
+		d.size += child.size
+		d.count += child.count
+		for key, n := range child.contents {
+			d.contents[key] += n
+		}
+	}
+	sort.Strings(entries)
+
+	h := sha3.New256()
+	for _, entry := range entries {
+		fmt.Fprintf(h, "%d:%s", len(entry), entry)
+	}
+	d.hash = hex.EncodeToString(h.Sum(nil))
+}
+
+// directoryGroup is a set of directories with identical trees.
+type directoryGroup struct {
+	Hash        string   `json:"hash"`
+	Size        int64    `json:"size"`
+	Files       int      `json:"files"`
+	Count       int      `json:"count"`
+	Total       int64    `json:"total"`
+	Wasted      int64    `json:"wasted"`
+	Directories []string `json:"directories"`
+}
+
+// similarDirectories are two directories sharing Shared bytes of content,
+// Similarity is the percentage of the larger directory.
+type similarDirectories struct {
+	Similarity  float64   `json:"similarity"`
+	Shared      int64     `json:"shared"`
+	Sizes       [2]int64  `json:"sizes"`
+	Directories [2]string `json:"directories"`
+}
+
+type dirPair struct {
+	a, b *dirNode
+}
+
+func newDirPair(a, b *dirNode) dirPair {
+	if a.path > b.path {
+		a, b = b, a
+	}
+	return dirPair{a: a, b: b}
+}
+
+// within reports whether both directories of the pair are in the directories
+// of one of the pairs, in either order.
+func (p dirPair) within(pairs []dirPair) bool {
+	in := func(d, ancestor *dirNode) bool {
+		return d == ancestor || d.below(ancestor)
+	}
+	for _, q := range pairs {
+		if in(p.a, q.a) && in(p.b, q.b) || in(p.a, q.b) && in(p.b, q.a) {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateDirectories finds the groups of identical directories and the
+// pairs of directories sharing at least similar percent of their content. The
+// first directory of a group is kept, directories below the other copies are
+// redundant and left out of the following groups and pairs.
+func duplicateDirectories(entries []FileEntry, similar float64, filter func(path string, size int64) bool) ([]directoryGroup, []similarDirectories) {
+	t := newDirTree(entries)
+
+	byHash := map[string][]*dirNode{}
+	for _, d := range t.dirs {
+		if d.considered() && filter(d.path, d.size) {
+			byHash[d.hash] = append(byHash[d.hash], d)
+		}
+	}
+	candidates := [][]*dirNode{}
+	for _, dirs := range byHash {
+		if len(dirs) > 1 {
+			sort.Slice(dirs, func(i, j int) bool { return dirs[i].path < dirs[j].path })
+			candidates = append(candidates, dirs)
+		}
+	}
+	// parents first, so the copies within redundant directories are known
+	depth := func(dirs []*dirNode) int {
+		min := -1
+		for _, d := range dirs {
+			if n := strings.Count(d.path, string(filepath.Separator)); min < 0 || n < min {
+				min = n
+			}
+		}
+		return min
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if a, b := depth(candidates[i]), depth(candidates[j]); a != b {
+			return a < b
+		}
+		return candidates[i][0].path < candidates[j][0].path
+	})
+
+	groups := []directoryGroup{}
+	redundant := []string{}
+	for _, dirs := range candidates {
+		group := directoryGroup{Hash: dirs[0].hash, Size: dirs[0].size, Files: dirs[0].count}
+		for _, d := range dirs {
+			if !below(redundant, d.path) {
+				group.Directories = append(group.Directories, d.path)
+			}
+		}
+		if len(group.Directories) < 2 {
+			continue
+		}
+		redundant = append(redundant, group.Directories[1:]...)
+		group.Count = len(group.Directories)
+		group.Total = group.Size * int64(group.Count)
+		group.Wasted = group.Size * int64(group.Count-1)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Wasted != groups[j].Wasted {
+			return groups[i].Wasted > groups[j].Wasted
+		}
+		return groups[i].Directories[0] < groups[j].Directories[0]
+	})
+
+	if similar <= 0 {
+		return groups, nil
+	}
+	return groups, t.similar(similar, func(path string, size int64) bool {
+		return filter(path, size) && !below(redundant, path)
+	})
+}
+
+// commonContent is the number of copies beyond which a file, like a license
+// or an empty __init__.py, is too common to pair directories up.
+const commonContent = 100
+
+// similar finds the pairs of unrelated directories sharing at least percent of
+// their content which are not identical, whose parents are no such pair and
+// which are not within the directories of a pair found already. Both
+// directories have to pass the filter.
+//
+// A pair shares at most the size of the smaller directory, so only
+// directories of similar size are compared. The candidates of a directory
+// come from its rarest content: once the content left could not reach the
+// threshold on its own, every similar directory shares some of the content
+// looked at. Content with more than commonContent copies is never looked at.
+func (t *dirTree) similar(percent float64, filter func(path string, size int64) bool) []similarDirectories {
+	holders := map[string][]*dirNode{}
+	copies := map[string]int{}
+	for _, d := range t.dirs {
+		for _, file := range d.files {
+			copies[file.key]++
+		}
+		if d.considered() {
+			for key := range d.contents {
+				holders[key] = append(holders[key], d)
+			}
+		}
+	}
+
+	shared := map[dirPair]int64{}
+	sharedBytes := func(pair dirPair) int64 {
+		if n, ok := shared[pair]; ok {
+			return n
+		}
+		a, b := pair.a, pair.b
+		n := int64(0)
+		if a.considered() && b.considered() && a.hash != b.hash && !a.below(b) && !b.below(a) {
+			if len(b.contents) < len(a.contents) {
+				a, b = b, a
+			}
+			for key, count := range a.contents {
+				if other := b.contents[key]; other < count {
+					count = other
+				}
+				n += int64(count) * t.sizes[key]
+			}
+		}
+		shared[pair] = n
+		return n
+	}
+	similarity := func(pair dirPair) float64 {
+		larger := pair.a.size
+		if pair.b.size > larger {
+			larger = pair.b.size
+		}
+		return 100 * float64(sharedBytes(pair)) / float64(larger)
+	}
+	related := func(pair dirPair) bool {
+		a, b := pair.a.parent, pair.b.parent
+		if a == nil || b == nil || a == b {
+			return false
+		}
+		return a.hash == b.hash && a.considered() || similarity(newDirPair(a, b)) >= percent
+	}
+	comparable := func(a, b *dirNode) bool {
+		smaller, larger := a.size, b.size
+		if smaller > larger {
+			smaller, larger = larger, smaller
+		}
+		return 100*float64(smaller) >= percent*float64(larger)
+	}
+
+	candidates := []dirPair{}
+	compared := map[dirPair]bool{}
+	for _, a := range t.dirs {
+		if !a.considered() || !filter(a.path, a.size) {
+			continue
+		}
+		keys := []string{}
+		left := int64(0)
+		for key, n := range a.contents {
+			left += int64(n) * t.sizes[key]
+			if copies[key] <= commonContent {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if m, n := len(holders[keys[i]]), len(holders[keys[j]]); m != n {
+				return m < n
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			if 100*float64(left) < percent*float64(a.size) {
+				break
+			}
+			left -= int64(a.contents[key]) * t.sizes[key]
+			for _, b := range holders[key] {
+				pair := newDirPair(a, b)
+				if b == a || compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				if !comparable(a, b) || !filter(b.path, b.size) || similarity(pair) < percent || related(pair) {
+					continue
+				}
+				candidates = append(candidates, pair)
+			}
+		}
+	}
+	// parents first, a pair within the directories of a reported pair only
+	// repeats it
+	depth := func(pair dirPair) int {
+		return strings.Count(pair.a.path, string(filepath.Separator)) + strings.Count(pair.b.path, string(filepath.Separator))
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if a, b := depth(candidates[i]), depth(candidates[j]); a != b {
+			return a < b
+		}
+		return candidates[i].a.path+"\x00"+candidates[i].b.path < candidates[j].a.path+"\x00"+candidates[j].b.path
+	})
+	reported := []dirPair{}
+	for _, pair := range candidates {
+		if pair.within(reported) {
+			continue
+		}
+		reported = append(reported, pair)
+	}
+
+	pairs := []similarDirectories{}
+	for _, pair := range reported {
+		pairs = append(pairs, similarDirectories{
+			Similarity:  similarity(pair),
+			Shared:      sharedBytes(pair),
+			Sizes:       [2]int64{pair.a.size, pair.b.size},
+			Directories: [2]string{pair.a.path, pair.b.path},
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Shared != pairs[j].Shared {
+			return pairs[i].Shared > pairs[j].Shared
+		}
+		return strings.Join(pairs[i].Directories[:], "\x00") < strings.Join(pairs[j].Directories[:], "\x00")
+	})
+	return pairs
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func anyDir(path string, size int64) bool {
+	return true
+}
+
+func testEntry(path, hash string, size int64) FileEntry {
+	return FileEntry{ID: filepath.FromSlash(path), Hash: hash, Size: size}
+}
+
+func TestSimilarDirectories(t *testing.T) {
+	entries := []FileEntry{
+		testEntry("/r/a/x", "x", 100), testEntry("/r/a/y", "y", 100), testEntry("/r/a/z", "z", 100), testEntry("/r/a/u", "u", 50),
+		testEntry("/r/b/x", "x", 100), testEntry("/r/b/y", "y", 100), testEntry("/r/b/z", "z", 100), testEntry("/r/b/v", "v", 10),
+		// shares too little
+		testEntry("/r/c/x", "x", 100), testEntry("/r/c/w", "w", 400),
+	}
+	_, similar := duplicateDirectories(entries, 80, anyDir)
+	want := []similarDirectories{{
+		Similarity:  300.0 / 350 * 100,
+		Shared:      300,
+		Sizes:       [2]int64{350, 310},
+		Directories: [2]string{filepath.FromSlash("/r/a"), filepath.FromSlash("/r/b")},
+	}}
+	if !reflect.DeepEqual(similar, want) {
+		t.Errorf("similar %+v, want %+v", similar, want)
+	}
+}
+
+func TestSimilarCommonContent(t *testing.T) {
+	const dirs = 8000
+	entries := []FileEntry{}
+	for i := 0; i < dirs; i++ {
+		// the license makes up most of every directory
+		dir := fmt.Sprintf("/r/d%04d", i)
+		entries = append(entries, testEntry(dir+"/LICENSE", "license", 1000), testEntry(dir+"/main", fmt.Sprint("main", i), 100))
+	}
+	entries = append(entries,
+		testEntry("/r/a/LICENSE", "license", 1000), testEntry("/r/a/data", "data", 5000), testEntry("/r/a/x", "x", 10),
+		testEntry("/r/b/LICENSE", "license", 1000), testEntry("/r/b/data", "data", 5000), testEntry("/r/b/y", "y", 10))
+
+	start := time.Now()
+	_, similar := duplicateDirectories(entries, 50, anyDir)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s", elapsed)
+	}
+	if len(similar) != 1 || similar[0].Shared != 6000 ||
+		similar[0].Directories != [2]string{filepath.FromSlash("/r/a"), filepath.FromSlash("/r/b")} {
+		t.Errorf("similar %+v", similar)
+	}
+}
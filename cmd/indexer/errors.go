@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiurthorn/go/lib"
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	errorPermission = "permission"
+	errorVanished   = "vanished"
+	errorUnreadable = "unreadable"
+)
+
+// ScanError records a file or directory the last scan below its root could not
+// read. It is removed once a scan gets through.
+type ScanError struct {
+	ID      string    `badgerhold:"key" boltholdKey:"ID" json:"path"`
+	Kind    string    `badgerhold:"index" boltholdIndex:"Kind" json:"kind"`
+	Op      string    `json:"op"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return errorPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return errorVanished
+	}
+	return errorUnreadable
+}
+
+// scanErrors collects the errors of a scan, only the scanning goroutine
+// records them.
+type scanErrors struct {
+	paths  *lib.StringSet
+	counts map[string]int
+}
+
+var failures = &scanErrors{paths: lib.NewStringSet(), counts: map[string]int{}}
+
+// record logs the failed operation on path and stores it in the database.
+func (e *scanErrors) record(op, path string, err error) {
+	log.Printf("%s '%s': %v", op, path, err)
+	entry := ScanError{ID: path, Kind: errorKind(err), Op: op, Message: err.Error(), Time: time.Now()}
+	if err := store.Upsert(path, entry); err != nil {
+		log.Printf("store error of '%s': %v", path, err)
+	}
+	if !e.paths.Has(path) {
+		e.paths.Add(path)
+		e.counts[entry.Kind]++
+	}
+}
+
+// resume takes over the errors the interrupted scan recorded below roots
+// since start, the resumed scan does not visit their paths again.
+func (e *scanErrors) resume(roots []string, start time.Time) error {
+	entries := []ScanError{}
+	if err := store.Find(&entries, &bh.Query{}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if below(roots, entry.ID) && !entry.Time.Before(start) && !e.paths.Has(entry.ID) {
+			e.paths.Add(entry.ID)
+			e.counts[entry.Kind]++
+		}
+	}
+	return nil
+}
+
+func (e *scanErrors) Len() int {
+	return e.paths.Len()
+}
+
+func (e *scanErrors) String() string {
+	kinds := []string{}
+	for kind, n := range e.counts {
+		kinds = append(kinds, fmt.Sprintf("%d %s", n, kind))
+	}
+	sort.Strings(kinds)
+	return fmt.Sprintf("%d errors: %s", e.Len(), strings.Join(kinds, ", "))
+}
+
+// clear removes the errors of earlier scans below roots which did not occur
+// again.
+func (e *scanErrors) clear(roots []string) {
+	entries := []ScanError{}
+	if err := store.Find(&entries, &bh.Query{}); err != nil {
+		log.Printf("load errors: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if below(roots, entry.ID) && !e.paths.Has(entry.ID) {
+			if err := store.Delete(entry.ID, ScanError{}); err != nil {
+				log.Printf("remove error of '%s': %v", entry.ID, err)
+			}
+		}
+	}
+}
+
+// loadErrors returns the recorded errors which pass the filter sorted by path.
+func loadErrors(filter func(path string) bool) ([]ScanError, error) {
+	entries := []ScanError{}
+	if err := store.Find(&entries, &bh.Query{}); err != nil {
+		return nil, err
+	}
+	selected := []ScanError{}
+	for _, entry := range entries {
+		if filter(entry.ID) {
+			selected = append(selected, entry)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].ID < selected[j].ID })
+	return selected, nil
+}
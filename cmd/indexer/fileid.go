@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID returns the device and inode of a file.
+func fileID(info fs.FileInfo) (device, inode uint64) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev), uint64(stat.Ino)
+	}
+	return 0, 0
+}
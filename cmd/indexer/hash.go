@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	bh "github.com/timshannon/badgerhold/v4"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// defaultHash is the algorithm of new databases and of databases built before
+// the algorithm was recorded.
+const defaultHash = "sha3-256"
+
+// noHash disables the strong hash.
+const noHash = "none"
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha3-256": sha3.New256,
+	"sha256":   sha256.New,
+	"blake2b-256": func() hash.Hash {
+		// only fails for keys longer than 64 bytes
+		h, _ := blake2b.New256(nil)
+		return h
+	},
+	"blake3": func() hash.Hash { return blake3.New() },
+	"xxhash": func() hash.Hash { return xxhash.New() },
+}
+
+func hashNames() string {
+	names := make([]string, 0, len(hashAlgorithms))
+	for name := range hashAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// algorithm is a hash algorithm flag, the empty value keeps the algorithm
+// recorded in the database.
+type algorithm string
+
+func (a *algorithm) String() string {
+	return string(*a)
+}
+
+func (a *algorithm) Set(value string) error {
+	if _, ok := hashAlgorithms[value]; !ok && value != noHash {
+		return fmt.Errorf("unknown hash algorithm '%s', use %s", value, hashNames())
+	}
+	*a = algorithm(value)
+	return nil
+}
+
+// Settings records the hash algorithms of a database, so hashes of different
+// algorithms are never mixed. Strong names an optional second algorithm whose
+// full hash is stored together with the one of Hash. Files are duplicates only
+// if both hashes match.
+type Settings struct {
+	ID     string `badgerhold:"key" boltholdKey:"ID"`
+	Hash   string
+	Strong string
+}
+
+const settingsKey = "settings"
+
+// loadSettings returns the algorithms recorded in the database and records
+// the requested ones for a new database. It fails if the request differs from
+// the recorded algorithms.
+func loadSettings(hash, strong algorithm) (Settings, error) {
+	var settings Settings
+	err := store.Get(settingsKey, &settings)
+	if errors.Is(err, bh.ErrNotFound) {
+		settings = Settings{ID: settingsKey, Hash: string(hash), Strong: string(strong)}
+		count, err := store.Count(FileEntry{}, &bh.Query{})
+		if err != nil {
+			return settings, err
+		}
+		if count > 0 {
+			settings.Hash, settings.Strong = defaultHash, ""
+			if err := migrate(); err != nil {
+				return settings, err
+			}
+		}
+		if settings.Hash == "" {
+			settings.Hash = defaultHash
+		}
+		if settings.Strong == noHash {
+			settings.Strong = ""
+		}
+		if err := settings.check(hash, strong); err != nil {
+			return settings, err
+		}
+		return settings, store.Insert(settingsKey, settings)
+	}
+	if err != nil {
+		return settings, err
+	}
+	return settings, settings.check(hash, strong)
+}
+
+// readSettings returns the algorithms recorded in the database without
+// recording any, a database without settings has no hashes yet.
+func readSettings() (Settings, error) {
+	var settings Settings
+	err := store.Get(settingsKey, &settings)
+	if errors.Is(err, bh.ErrNotFound) {
+		return Settings{ID: settingsKey, Hash: defaultHash}, nil
+	}
+	return settings, err
+}
+
+func (s *Settings) check(hash, strong algorithm) error {
+	if hash != "" && string(hash) != s.Hash {
+		return fmt.Errorf("database uses hash %s, not %s", s.Hash, hash)
+	}
+	requested := string(strong)
+	if requested == noHash {
+		requested = ""
+	}
+	if strong != "" && requested != s.Strong {
+		recorded := s.Strong
+		if recorded == "" {
+			recorded = noHash
+		}
+		return fmt.Errorf("database uses strong hash %s, not %s", recorded, strong)
+	}
+	return nil
+}
+
+func (s Settings) String() string {
+	if s.Strong == "" {
+		return s.Hash
+	}
+	return s.Hash + "+" + s.Strong
+}
+
+// hashers holds the hash states of one worker.
+type hashers struct {
+	hash   hash.Hash
+	strong hash.Hash
+}
+
+func (s *Settings) hashers() *hashers {
+	h := &hashers{hash: hashAlgorithms[s.Hash]()}
+	if s.Strong != "" {
+		h.strong = hashAlgorithms[s.Strong]()
+	}
+	return h
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -10,85 +12,126 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"sync"
 	"syscall"
 	"time"
 
 	"github.com/fiurthorn/go/lib"
 	bh "github.com/timshannon/badgerhold/v4"
-
 	// bh "github.com/timshannon/bolthold"
-
-	"golang.org/x/crypto/sha3"
 )
 
-type HashEntry struct {
-	ID   uint64 `badgerhold:"key" boltholdKey:"ID"`
-	Hash string `badgerhold:"index" boltholdIndex:"Hash"`
-
-	Files *lib.StringSet
-}
+var store *bh.Store
 
-type FileEntry struct {
-	ID   string `badgerhold:"key" boltholdKey:"ID"`
-	Size int64  `badgerhold:"index" boltholdIndex:"Size"`
+// interruptContext returns a context which is cancelled on the first SIGINT or
+// SIGTERM, a second signal terminates the process right away.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		select {
+		case s := <-signals:
+			log.Printf("terminate: %v, interrupt again to quit at once", s)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
 }
 
-var wg = sync.WaitGroup{}
-var pool = make(chan string, 64)
-var store *bh.Store
-var quit = false
+var commands = map[string]func(args []string){
+	"scan":   scan,
+	"report": report,
+	"dedup":  dedup,
 
-func signalHandler(cancel chan os.Signal) {
-	signal.Notify(cancel, syscall.SIGTERM, syscall.SIGINT)
+	"lookup":   lookup,
+	"copies":   copies,
+	"contains": contains,
+	"size":     sizeRange,
+}
 
-	for signal := range cancel {
-		log.Print("terminate:", signal)
-		quit = true
+func main() {
+	name, args := "scan", os.Args[1:]
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		} else if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			fmt.Fprintf(os.Stderr, "Usage of %s: <command> [flags] (default command scan)\n", filepath.Base(os.Args[0]))
+			for _, command := range []string{"scan", "report", "dedup", "lookup", "copies", "contains", "size"} {
+				fmt.Fprintf(os.Stderr, "  %s\n", command)
+			}
+			os.Exit(2)
+		}
 	}
+	commands[name](args)
 }
 
-func main() {
-	go signalHandler(make(chan os.Signal))
+var stats scanStats
 
-	start := time.Now()
-	go inserter()
-	for i := 0; i < 4; i++ {
-		wg.Add(1)
-		go worker(i, pool, sha3.New256())
-	}
-	home, err := os.UserHomeDir()
-	if err != nil {
-		log.Panic(err)
-	}
-	dir := filepath.Join(home, "indexer")
+func scan(args []string) {
+	options := parseScanOptions(args)
+	ctx, cancel := interruptContext()
+	defer cancel()
 
-	// //badger
-	options := bh.DefaultOptions
-	options.Dir = dir
-	options.ValueDir = dir
-	store, err = bh.Open(options)
+	start := time.Now()
+	store = openStore(options.db)
+	defer store.Close()
 
-	// bolt
-	// store, err = bh.Open(filepath.Join(dir, "bold.db"), 0666, &bh.Options{})
+	progress := newProgress(options)
+	if options.resume {
+		saved, err := loadProgress()
+		if err != nil {
+			store.Close()
+			if errors.Is(err, bh.ErrNotFound) {
+				log.Fatal("no interrupted scan to resume")
+			}
+			log.Fatal(err)
+		}
+		progress = saved
+		options = parseScanOptions(append(append([]string{}, progress.state.Flags...), progress.state.Roots...))
+		log.Printf("resume %s of %s from %s", progress.state.Phase, progress.state.Roots, progress.state.Time.Format(time.RFC3339))
+		if err := failures.resume(options.roots, progress.state.Start); err != nil {
+			store.Close()
+			log.Fatal(err)
+		}
+	}
 
+	settings, err := loadSettings(options.hash, options.strong)
 	if err != nil {
-		log.Panic(err)
+		store.Close()
+		log.Fatal(err)
+	}
+	log.Println("hash", settings)
+
+	if progress.state.Phase == phaseWalk {
+		walkRoots(ctx, options, &progress.state, func(path string, info fs.FileInfo) {
+			visitor(path, info)
+			progress.visited(path)
+		}, failures.record)
+		if ctx.Err() == nil {
+			progress.state.Phase = phaseHash
+			log.Println("walked through")
+		}
+		progress.save()
 	}
-	defer store.Close()
 
-	filepath.WalkDir(filepath.Join(home, "Downloads"), walker)
-	log.Println("walked through")
-	close(pool)
-	log.Println("waiting")
-	wg.Wait()
-	close(queue)
-	<-ready
+	if ctx.Err() == nil {
+		stats.removed = prune(options.roots, seen)
+	}
+	if ctx.Err() == nil {
+		hashCandidates(ctx, options.workers, settings, changed)
+	}
+	if ctx.Err() == nil {
+		failures.clear(options.roots)
+	}
 	d := time.Since(start)
 
-	if !quit {
+	if ctx.Err() == nil {
+		progress.done()
+
 		results := []HashEntry{}
-		err = store.Find(&results, (*bh.Query)(bh.Where("Files").MatchFunc(func(ra *bh.RecordAccess) (bool, error) {
+		err := store.Find(&results, (*bh.Query)(bh.Where("Files").MatchFunc(func(ra *bh.RecordAccess) (bool, error) {
 			if files, ok := ra.Field().(*lib.StringSet); ok {
 				return files.Len() > 1, nil
 			}
@@ -112,98 +155,107 @@ func main() {
 		}
 		log.Println(len(results), "/", count, "/", count2)
 	}
-	log.Println(insert, "/", visit)
+	log.Println(&stats)
 	log.Println("time", d)
+
+	if ctx.Err() != nil {
+		log.Println("interrupted, continue with scan -resume")
+		store.Close()
+		os.Exit(130)
+	}
+	if failures.Len() > 0 {
+		log.Println(failures)
+		store.Close()
+		os.Exit(1)
+	}
 }
 
-type FileHash struct {
-	Hash string
-	File string
-	Size int64
+// seen collects the files found by the walk and changed the new or modified
+// ones, only the walking goroutine touches them.
+var seen = lib.NewStringSet()
+var changed = lib.NewStringSet()
+
+// visitor records new and modified files, their hashes are dropped until the
+// pipeline decides whether they are needed.
+func visitor(path string, info fs.FileInfo) {
+	stats.visited++
+	seen.Add(path)
+
+	var entry FileEntry
+	err := store.Get(path, &entry)
+	if err == nil && entry.unchanged(info) {
+		stats.unchanged++
+		return
+	}
+	if err == nil {
+		unlink(entry)
+	}
+	changed.Add(path)
+	if err := store.Upsert(path, newFileEntry(path, info)); err != nil {
+		log.Printf("store file '%s': %v", path, err)
+	}
 }
 
-var queue = make(chan FileHash, 20)
-var ready = make(chan struct{})
-var insert = 0
-
-func inserter() {
-	for entry := range queue {
-		insert++
-		var result HashEntry
-		store.FindOne(&result, (*bh.Query)(bh.Where("Hash").Eq(entry.Hash).Index("Hash")))
-		if result.ID > 0 || (result.Files != nil && result.Files.Len() > 0) {
-			if has := result.Files.Has(entry.File); !has {
-				result.Files.Add(entry.File)
-				// log.Println("update", entry.File)
-				store.Update(result.ID, result)
-				store.Insert(entry.File, FileEntry{Size: entry.Size})
-			}
-			continue
-		}
+// contextReader fails once ctx is done, so hashing a large file stops
+// promptly.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-		data := HashEntry{
-			Hash:  entry.Hash,
-			Files: lib.NewStringSetWith(entry.File),
-		}
-		// log.Println("insert", entry.File)
-		store.Insert(bh.NextSequence(), data)
-		store.Insert(entry.File, FileEntry{Size: entry.Size})
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
-	ready <- struct{}{}
+	return r.r.Read(p)
 }
 
-var visit = 0
-
-func walker(path string, d fs.DirEntry, err error) error {
+// calcHash returns the digests of the file content for all hashes and the
+// number of bytes read.
+func calcHash(ctx context.Context, path string, hs ...hash.Hash) ([]string, int64, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("skip dir '%s' with error: %w", path, err)
+		return nil, 0, err
 	}
+	defer file.Close()
 
-	if d.IsDir() && filepath.Base(path) == ".git" {
-		return fs.SkipDir
+	writers := make([]io.Writer, len(hs))
+	for i, h := range hs {
+		h.Reset()
+		writers[i] = h
 	}
-
-	if d.Type().IsRegular() {
-		if !quit {
-			visit++
-			pool <- path
-		}
+	size, err := io.Copy(io.MultiWriter(writers...), contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return nil, size, err
 	}
 
-	return nil
-}
-
-func worker(i int, pool chan string, h hash.Hash) {
-	for path := range pool {
-		if !quit {
-			logHash(i, path, h)
-		}
+	digests := make([]string, len(hs))
+	for i, h := range hs {
+		digests[i] = hex.EncodeToString(h.Sum(nil))
 	}
-	wg.Done()
-}
-
-func logHash(i int, path string, h hash.Hash) string {
-	start := time.Now()
-	hash, size := calcHash(path, h)
-	log.Printf("[%2d]: [%10s] %s: %s\r", i, time.Since(start), hash, path)
-	queue <- FileHash{Hash: hash, File: path, Size: size}
-	return hash
+	return digests, size, nil
 }
 
-func calcHash(path string, h hash.Hash) (string, int64) {
+// calcPartialHash hashes the size and the first and last partialBlock bytes of
+// a file larger than two blocks.
+func calcPartialHash(path string, size int64, h hash.Hash) (string, int64, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("skip dir '%s' with error: %v", path, err)
+		return "", 0, err
 	}
 	defer file.Close()
 
-	stat, err := file.Stat()
-	if err != nil {
-		log.Printf("skip dir '%s' with error: %v", path, err)
-	}
-	size := stat.Size()
-
 	h.Reset()
-	io.Copy(h, file)
-	return hex.EncodeToString(h.Sum(nil)), size
+	fmt.Fprint(h, size, ":")
+	buffer := make([]byte, partialBlock)
+	read := int64(0)
+	for _, offset := range []int64{0, size - partialBlock} {
+		n, err := file.ReadAt(buffer, offset)
+		read += int64(n)
+		if err != nil {
+			return "", read, err
+		}
+		h.Write(buffer)
+	}
+	return hex.EncodeToString(h.Sum(nil)), read, nil
 }
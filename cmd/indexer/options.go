@@ -0,0 +1,379 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type symlinkPolicy string
+
+const (
+	// symlinksSkip ignores all symbolic links
+	symlinksSkip symlinkPolicy = "skip"
+	// symlinksFiles indexes links to regular files but does not descend into
+	// linked directories
+	symlinksFiles symlinkPolicy = "files"
+	// symlinksAll follows links to files and directories, directory cycles
+	// are detected and skipped
+	symlinksAll symlinkPolicy = "all"
+)
+
+func (p *symlinkPolicy) String() string {
+	return string(*p)
+}
+
+func (p *symlinkPolicy) Set(value string) error {
+	switch policy := symlinkPolicy(value); policy {
+	case symlinksSkip, symlinksFiles, symlinksAll:
+		*p = policy
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy '%s', use skip, files or all", value)
+}
+
+// patternList is a repeatable flag of glob patterns. The first pattern given
+// replaces the defaults.
+type patternList struct {
+	patterns []string
+	defaults bool
+}
+
+func (l *patternList) String() string {
+	return strings.Join(l.patterns, ",")
+}
+
+func (l *patternList) Set(value string) error {
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("pattern '%s': %w", value, err)
+	}
+	if l.defaults {
+		l.patterns, l.defaults = nil, false
+	}
+	l.patterns = append(l.patterns, value)
+	return nil
+}
+
+// match checks a pattern with a separator against the slash separated path
+// relative to the scan root and a pattern without one against the base name.
+func (l *patternList) match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	base := rel[strings.LastIndex(rel, "/")+1:]
+	for _, pattern := range l.patterns {
+		name := base
+		if strings.Contains(pattern, "/") {
+			name = rel
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// byteSize is a size flag accepting the suffixes K, M, G and T for binary
+// multiples.
+type byteSize int64
+
+func (s *byteSize) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *byteSize) Set(value string) error {
+	units := map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	unit := ""
+	if i := strings.LastIndexAny(number, "KMGT"); i >= 0 && i == len(number)-1 {
+		number, unit = number[:i], number[i:]
+	}
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("invalid size '%s'", value)
+	}
+	*s = byteSize(size * units[unit])
+	return nil
+}
+
+type scanOptions struct {
+	db       string
+	roots    []string
+	include  patternList
+	exclude  patternList
+	symlinks symlinkPolicy
+	maxDepth int
+	minSize  byteSize
+	maxSize  byteSize
+	workers  int
+	hash     algorithm
+	strong   algorithm
+	resume   bool
+	// flags are the arguments before the roots, saved to resume the scan
+	flags []string
+}
+
+func defaultDatabase() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "indexer"
+	}
+	return filepath.Join(home, "indexer")
+}
+
+func databaseFlag(flags *flag.FlagSet, db *string) {
+	flags.StringVar(db, "db", defaultDatabase(), "badger database `directory`")
+}
+
+func usage(flags *flag.FlagSet, args string) func() {
+	return func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s %s: %s\n", filepath.Base(os.Args[0]), flags.Name(), args)
+		flags.PrintDefaults()
+	}
+}
+
+func parseScanOptions(args []string) *scanOptions {
+	options := &scanOptions{
+		exclude:  patternList{patterns: []string{".git"}, defaults: true},
+		symlinks: symlinksSkip,
+	}
+
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	flags.Usage = usage(flags, "[flags] [root ...] (default root ~/Downloads)")
+	databaseFlag(flags, &options.db)
+	flags.Var(&options.include, "include", "only index files matching the glob `pattern` (repeatable)")
+	flags.Var(&options.exclude, "exclude", "skip files and directories matching the glob `pattern` (repeatable)")
+	flags.Var(&options.symlinks, "symlinks", "symbolic link `policy`: skip, files or all")
+	flags.IntVar(&options.maxDepth, "max-depth", -1, "maximum directory `depth` below a root, 0 indexes only the files in the root, -1 is unlimited")
+	flags.Var(&options.minSize, "min-size", "skip files smaller than `size` (suffixes K, M, G, T)")
+	flags.Var(&options.maxSize, "max-size", "skip files larger than `size`, 0 is unlimited")
+	flags.IntVar(&options.workers, "workers", 4, "number of hashing `workers`")
+	flags.Var(&options.hash, "hash", "hash `algorithm` of a new database: "+hashNames()+" (default "+defaultHash+")")
+	flags.Var(&options.strong, "strong", "second hash `algorithm` stored with the full hash of a new database, or none")
+	flags.BoolVar(&options.resume, "resume", false, "continue the interrupted scan with its roots and flags")
+	flags.Parse(args)
+
+	if options.hash == noHash {
+		fmt.Fprintln(flags.Output(), "the hash algorithm is required")
+		os.Exit(2)
+	}
+	if options.resume && flags.NArg() > 0 {
+		fmt.Fprintln(flags.Output(), "-resume continues the roots of the interrupted scan")
+		os.Exit(2)
+	}
+	options.flags = args[:len(args)-flags.NArg()]
+
+	options.roots = flags.Args()
+	if len(options.roots) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintln(flags.Output(), "no root given:", err)
+			os.Exit(2)
+		}
+		options.roots = []string{filepath.Join(home, "Downloads")}
+	}
+	for i, root := range options.roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			fmt.Fprintf(flags.Output(), "root '%s': %v\n", root, err)
+			os.Exit(2)
+		}
+		options.roots[i] = abs
+	}
+	if options.workers < 1 {
+		options.workers = 1
+	}
+	return options
+}
+
+// accept applies the include, exclude and size filters to a regular file.
+func (o *scanOptions) accept(rel string, size int64) bool {
+	if len(o.include.patterns) > 0 && !o.include.match(rel) {
+		return false
+	}
+	if o.exclude.match(rel) {
+		return false
+	}
+	if size < int64(o.minSize) || o.maxSize > 0 && size > int64(o.maxSize) {
+		return false
+	}
+	return true
+}
+
+type reportFormat string
+
+const (
+	formatHuman reportFormat = "human"
+	formatJSON  reportFormat = "json"
+	formatCSV   reportFormat = "csv"
+)
+
+func (f *reportFormat) String() string {
+	return string(*f)
+}
+
+func (f *reportFormat) Set(value string) error {
+	switch format := reportFormat(value); format {
+	case formatHuman, formatJSON, formatCSV:
+		*f = format
+		return nil
+	}
+	return fmt.Errorf("unknown format '%s', use human, json or csv", value)
+}
+
+// pathList is a repeatable flag of paths which are made absolute.
+type pathList []string
+
+func (l *pathList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *pathList) Set(value string) error {
+	abs, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, abs)
+	return nil
+}
+
+type reportOptions struct {
+	db       string
+	format   reportFormat
+	prefixes pathList
+	minSize  byteSize
+	dirs     bool
+	similar  float64
+}
+
+func parseReportOptions(args []string) *reportOptions {
+	options := &reportOptions{format: formatHuman}
+
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	flags.Usage = usage(flags, "[flags]")
+	databaseFlag(flags, &options.db)
+	flags.Var(&options.format, "format", "output `format`: human, json or csv")
+	flags.Var(&options.prefixes, "prefix", "only report files below the `path` (repeatable)")
+	flags.Var(&options.minSize, "min-size", "skip groups of files or directories smaller than `size`")
+	flags.BoolVar(&options.dirs, "dirs", true, "report identical directories instead of their files")
+	flags.Float64Var(&options.similar, "similar", 80, "report directories sharing at least `percent` of their content, 0 disables")
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	return options
+}
+
+type keepPolicy string
+
+const (
+	keepOldest    keepPolicy = "oldest"
+	keepNewest    keepPolicy = "newest"
+	keepShortest  keepPolicy = "shortest"
+	keepPreferred keepPolicy = "preferred"
+)
+
+func (p *keepPolicy) String() string {
+	return string(*p)
+}
+
+func (p *keepPolicy) Set(value string) error {
+	switch policy := keepPolicy(value); policy {
+	case keepOldest, keepNewest, keepShortest, keepPreferred:
+		*p = policy
+		return nil
+	}
+	return fmt.Errorf("unknown keep policy '%s', use oldest, newest, shortest or preferred", value)
+}
+
+type dedupAction string
+
+const (
+	actionDelete   dedupAction = "delete"
+	actionHardlink dedupAction = "hardlink"
+	actionSymlink  dedupAction = "symlink"
+	actionReflink  dedupAction = "reflink"
+)
+
+func (a *dedupAction) String() string {
+	return string(*a)
+}
+
+func (a *dedupAction) Set(value string) error {
+	switch action := dedupAction(value); action {
+	case actionDelete, actionHardlink, actionSymlink, actionReflink:
+		*a = action
+		return nil
+	}
+	return fmt.Errorf("unknown action '%s', use delete, hardlink, symlink or reflink", value)
+}
+
+type dedupOptions struct {
+	db       string
+	keep     keepPolicy
+	prefer   pathList
+	action   dedupAction
+	apply    bool
+	prefixes pathList
+	minSize  byteSize
+}
+
+func parseDedupOptions(args []string) *dedupOptions {
+	options := &dedupOptions{keep: keepShortest}
+
+	flags := flag.NewFlagSet("dedup", flag.ExitOnError)
+	flags.Usage = usage(flags, "-action action [flags]")
+	databaseFlag(flags, &options.db)
+	flags.Var(&options.keep, "keep", "`policy` choosing the file to keep: oldest, newest, shortest or preferred")
+	flags.Var(&options.prefer, "prefer", "preferred `directory` of the preferred policy (repeatable, in order)")
+	flags.Var(&options.action, "action", "replace duplicates by `action`: delete, hardlink, symlink or reflink")
+	flags.BoolVar(&options.apply, "apply", false, "change the files, otherwise only the preview is shown")
+	flags.Var(&options.prefixes, "prefix", "only deduplicate files below the `path` (repeatable)")
+	flags.Var(&options.minSize, "min-size", "skip groups of files smaller than `size`")
+	flags.Parse(args)
+
+	switch {
+	case flags.NArg() > 0:
+		flags.Usage()
+		os.Exit(2)
+	case options.action == "":
+		fmt.Fprintln(flags.Output(), "the action is required")
+		os.Exit(2)
+	case options.keep == keepPreferred && len(options.prefer) == 0:
+		fmt.Fprintln(flags.Output(), "the preferred policy requires -prefer")
+		os.Exit(2)
+	}
+	return options
+}
+
+type queryOptions struct {
+	db      string
+	minSize byteSize
+	maxSize byteSize
+	args    []string
+}
+
+// parseQueryOptions parses the flags of the query command name, the size
+// command takes a size range instead of arguments.
+func parseQueryOptions(name, arguments string, args []string) *queryOptions {
+	options := &queryOptions{}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = usage(flags, "[flags] "+arguments)
+	databaseFlag(flags, &options.db)
+	if name == "size" {
+		flags.Var(&options.minSize, "min", "smallest file `size` (suffixes K, M, G, T)")
+		flags.Var(&options.maxSize, "max", "largest file `size`, 0 is unlimited")
+	}
+	flags.Parse(args)
+
+	options.args = flags.Args()
+	if name == "size" && len(options.args) > 0 || name != "size" && len(options.args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	return options
+}
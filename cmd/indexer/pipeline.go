@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiurthorn/go/lib"
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+// partialBlock is the number of bytes hashed at the start and at the end of a
+// file to tell files of the same size apart. Files up to two blocks are hashed
+// completely instead.
+const partialBlock = 4 << 10
+
+type scanStats struct {
+	visited   int
+	unchanged int
+	removed   int
+	partial   int
+	full      int
+	// hashed is the size of all files a full hash of every new, modified or
+	// newly colliding file would read, read is what the full hashes actually
+	// read. The head and tail blocks read by the partial hashes are counted
+	// apart in overhead.
+	hashed   int64
+	read     int64
+	overhead int64
+}
+
+func (s *scanStats) String() string {
+	// files growing while they are hashed may be read beyond their size
+	saved := s.hashed - s.read
+	if saved < 0 {
+		saved = 0
+	}
+	return fmt.Sprintf("visited %d, unchanged %d, removed %d, partial hashes %d, full hashes %d, read %s of %s, saved %s, partial reads %s",
+		s.visited, s.unchanged, s.removed, s.partial, s.full,
+		humanSize(s.read), humanSize(s.hashed), humanSize(saved), humanSize(s.overhead))
+}
+
+func humanSize(size int64) string {
+	const units = "KMGTPE"
+	if size < 1<<10 && size > -1<<10 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, unit := float64(size)/(1<<10), 0
+	for (value >= 1<<10 || value <= -1<<10) && unit < len(units)-1 {
+		value /= 1 << 10
+		unit++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, units[unit])
+}
+
+// hashStageKind tells the stages of hashCandidates apart.
+type hashStageKind int
+
+const (
+	// partialStage hashes the head and tail blocks, small files completely
+	partialStage hashStageKind = iota
+	// fullStage hashes the whole file
+	fullStage
+)
+
+type hashResult struct {
+	entry FileEntry
+	read  int64
+	err   error
+	done  bool
+}
+
+// hashCandidates hashes the indexed files which may have duplicates in stages.
+// Files of a unique size are never read. Files sharing their size get a partial
+// hash and only files whose partial hash collides as well are hashed
+// completely. changed holds the files new or modified in this scan. The files
+// still missing a hash when ctx is done are hashed by the next scan.
+func hashCandidates(ctx context.Context, workers int, settings Settings, changed *lib.StringSet) {
+	entries := []FileEntry{}
+	if err := store.Find(&entries, &bh.Query{}); err != nil {
+		log.Printf("load files: %v", err)
+		return
+	}
+	counted := lib.NewStringSetWith(changed.Values()...)
+	for _, entry := range entries {
+		if changed.Has(entry.ID) {
+			stats.hashed += entry.Size
+		}
+	}
+
+	sizes := map[string][]int{}
+	for i, entry := range entries {
+		key := fmt.Sprint(entry.Size)
+		sizes[key] = append(sizes[key], i)
+	}
+	jobs := collisions(sizes, func(entry FileEntry) bool { return entry.Partial == "" }, entries)
+	stats.partial += hashStage(ctx, workers, settings, entries, jobs, counted, partialStage)
+
+	partials := map[string][]int{}
+	for _, group := range sizes {
+		if len(group) < 2 {
+			continue
+		}
+		for _, i := range group {
+			if entries[i].Partial != "" {
+				key := fmt.Sprint(entries[i].Size, ":", entries[i].Partial)
+				partials[key] = append(partials[key], i)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	jobs = collisions(partials, func(entry FileEntry) bool { return entry.Hash == "" }, entries)
+	stats.full += hashStage(ctx, workers, settings, entries, jobs, counted, fullStage)
+}
+
+// collisions returns the sorted indexes of the entries in groups of at least
+// two which still need a hash.
+func collisions(groups map[string][]int, missing func(entry FileEntry) bool, entries []FileEntry) []int {
+	jobs := []int{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		for _, i := range group {
+			if missing(entries[i]) {
+				jobs = append(jobs, i)
+			}
+		}
+	}
+	sort.Ints(jobs)
+	return jobs
+}
+
+// hashStage computes the hashes of the stage for the entries at jobs
+// concurrently and stores the results in the database and in entries. It
+// returns the number of hashed files. When ctx is done the running hashes are
+// aborted and the remaining jobs skipped.
+func hashStage(ctx context.Context, workers int, settings Settings, entries []FileEntry, jobs []int, counted *lib.StringSet, stage hashStageKind) int {
+	fn := fullHash
+	if stage == partialStage {
+		fn = partialHash
+	}
+	pool := sync.Pool{New: func() any { return settings.hashers() }}
+	results, err := lib.ParallelMap(ctx, lib.Parallelism{Concurrency: workers, Errors: lib.AllErrors}, jobs, func(ctx context.Context, i int) (hashResult, error) {
+		if ctx.Err() != nil {
+			return hashResult{}, nil
+		}
+		h := pool.Get().(*hashers)
+		defer pool.Put(h)
+
+		start := time.Now()
+		entry, read, err := fn(ctx, entries[i], h)
+		if ctx.Err() != nil {
+			// the hash is incomplete
+			return hashResult{read: read}, nil
+		}
+		if err != nil {
+			return hashResult{entry: entries[i], read: read, err: err}, nil
+		}
+		digest := entry.Hash
+		if digest == "" {
+			digest = entry.Partial
+		}
+		log.Printf("[%10s] %s: %s\r", time.Since(start), digest, entry.ID)
+		return hashResult{entry: entry, read: read, done: true}, nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Print(err)
+	}
+
+	hashed := 0
+	for k, i := range jobs {
+		result := results[k]
+		if stage == partialStage && entries[i].Size > 2*partialBlock {
+			stats.overhead += result.read
+		} else {
+			stats.read += result.read
+		}
+		if result.err != nil {
+			failures.record("hash", result.entry.ID, result.err)
+			if errorKind(result.err) == errorVanished {
+				forget(result.entry)
+			}
+			continue
+		}
+		if !result.done {
+			continue
+		}
+		hashed++
+		if !counted.Has(result.entry.ID) {
+			counted.Add(result.entry.ID)
+			stats.hashed += result.entry.Size
+		}
+		entries[i] = result.entry
+		if result.entry.Hash != "" {
+			index(result.entry)
+		} else if err := store.Upsert(result.entry.ID, result.entry); err != nil {
+			log.Printf("store file '%s': %v", result.entry.ID, err)
+		}
+	}
+	return hashed
+}
+
+// partialHash hashes the first and the last block of the file, small files
+// get their full hash right away.
+func partialHash(ctx context.Context, entry FileEntry, h *hashers) (FileEntry, int64, error) {
+	if entry.Size <= 2*partialBlock {
+		entry, read, err := fullHash(ctx, entry, h)
+		entry.Partial = entry.Hash
+		return entry, read, err
+	}
+	partial, read, err := calcPartialHash(entry.ID, entry.Size, h.hash)
+	if err != nil {
+		return entry, read, err
+	}
+	entry.Partial = partial
+	return entry, read, nil
+}
+
+// fullHash hashes the whole file, the strong hash is computed in the same
+// pass.
+func fullHash(ctx context.Context, entry FileEntry, h *hashers) (FileEntry, int64, error) {
+	if h.strong == nil {
+		digests, size, err := calcHash(ctx, entry.ID, h.hash)
+		if err != nil {
+			return entry, size, err
+		}
+		entry.Hash = digests[0]
+		return entry, size, nil
+	}
+	digests, size, err := calcHash(ctx, entry.ID, h.hash, h.strong)
+	if err != nil {
+		return entry, size, err
+	}
+	entry.Hash, entry.Strong = digests[0], digests[1]
+	return entry, size, nil
+}
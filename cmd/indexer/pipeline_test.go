@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fiurthorn/go/lib"
+)
+
+func TestHashCandidatesStats(t *testing.T) {
+	openTestStore(t)
+	settings, err := loadSettings("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, seen, changed = scanStats{}, lib.NewStringSet(), lib.NewStringSet()
+
+	block := func(b byte, blocks int) []byte {
+		return bytes.Repeat([]byte{b}, blocks*partialBlock)
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+	dir := t.TempDir()
+	files := map[string][]byte{
+		// same head and tail, only the full hash tells them apart
+		"large1": join(block('a', 1), block('b', 1), block('c', 1)),
+		"large2": join(block('a', 1), block('x', 1), block('c', 1)),
+		"large3": join(block('a', 1), block('b', 1), block('c', 1)),
+		// the partial hash tells them apart
+		"head1": join(block('1', 4), block('z', 1)),
+		"head2": join(block('2', 4), block('z', 1)),
+		// small files are hashed completely right away
+		"small1": []byte(strings.Repeat("s", 100)),
+		"small2": []byte(strings.Repeat("t", 100)),
+		// a unique size is never read
+		"unique": []byte(strings.Repeat("u", 50)),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		visitor(path, info)
+	}
+
+	hashCandidates(context.Background(), 2, settings, changed)
+	want := scanStats{
+		visited:  len(files),
+		partial:  7,
+		full:     3,
+		hashed:   3*3*partialBlock + 2*5*partialBlock + 2*100 + 50,
+		read:     3*3*partialBlock + 2*100,
+		overhead: 5 * 2 * partialBlock,
+	}
+	if stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+	if s := stats.String(); !strings.Contains(s, "saved 40.0 KiB") {
+		t.Errorf("stats %q", s)
+	}
+}
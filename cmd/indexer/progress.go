@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+const (
+	// phaseWalk walks the roots, Root and Position tell where to go on
+	phaseWalk = "walk"
+	// phaseHash prunes and hashes, the files still missing a hash are the
+	// pending work
+	phaseHash = "hash"
+)
+
+// ScanState is the progress of an interrupted scan. The walk visits the files
+// in lexical order, so the last visited file of the current root is enough
+// to continue. Start tells the errors of the interrupted scan from older
+// ones.
+type ScanState struct {
+	ID       string `badgerhold:"key" boltholdKey:"ID"`
+	Flags    []string
+	Roots    []string
+	Phase    string
+	Root     int
+	Position string
+	Start    time.Time
+	Time     time.Time
+}
+
+const stateKey = "scan"
+
+// progress tracks the state of the running scan and saves it at most once a
+// second.
+type progress struct {
+	state ScanState
+	saved time.Time
+}
+
+func newProgress(options *scanOptions) *progress {
+	return &progress{state: ScanState{
+		ID:    stateKey,
+		Flags: options.flags,
+		Roots: options.roots,
+		Phase: phaseWalk,
+		Start: time.Now(),
+	}}
+}
+
+// loadProgress returns the saved progress of an interrupted scan.
+func loadProgress() (*progress, error) {
+	p := &progress{}
+	if err := store.Get(stateKey, &p.state); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *progress) visited(path string) {
+	p.state.Position = path
+	if time.Since(p.saved) >= time.Second {
+		p.save()
+	}
+}
+
+func (p *progress) save() {
+	p.state.Time = time.Now()
+	p.saved = p.state.Time
+	if err := store.Upsert(stateKey, p.state); err != nil {
+		log.Printf("save progress: %v", err)
+	}
+}
+
+// done removes the progress of a completed scan.
+func (p *progress) done() {
+	if err := store.Delete(stateKey, ScanState{}); err != nil && !errors.Is(err, bh.ErrNotFound) {
+		log.Printf("remove progress: %v", err)
+	}
+}
+
+// walkOrder compares two paths in the order of the walk, which sorts the
+// names within each directory.
+func walkOrder(a, b string) int {
+	as := strings.Split(a, string(filepath.Separator))
+	bs := strings.Split(b, string(filepath.Separator))
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
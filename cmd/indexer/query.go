@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+// The query commands print one path per line and exit with 1 like grep if
+// nothing was found.
+
+// finish closes the store and exits with 1 if nothing was found.
+func finish(found bool) {
+	store.Close()
+	if !found {
+		os.Exit(1)
+	}
+}
+
+// lookup lists the files with a full hash or strong hash starting with the
+// given hex digests.
+func lookup(args []string) {
+	options := parseQueryOptions("lookup", "hash ...", args)
+	store = openExistingStore(options.db)
+
+	files := []string{}
+	for _, prefix := range options.args {
+		prefix = strings.ToLower(prefix)
+		results := []HashEntry{}
+		err := store.Find(&results, (*bh.Query)(bh.Where("Hash").HasPrefix(prefix).Or(bh.Where("Strong").HasPrefix(prefix))))
+		if err != nil {
+			log.Panic(err)
+		}
+		for _, result := range results {
+			files = append(files, result.Files.Values()...)
+		}
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		fmt.Println(file)
+	}
+	finish(len(files) > 0)
+}
+
+// copies lists the indexed files with the same content as the given files.
+func copies(args []string) {
+	options := parseQueryOptions("copies", "file ...", args)
+	store = openExistingStore(options.db)
+	settings := querySettings()
+
+	found := false
+	for _, file := range options.args {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			log.Panic(err)
+		}
+		matches, err := indexedCopies(settings, path)
+		if err != nil {
+			log.Printf("copies of '%s': %v", file, err)
+			continue
+		}
+		for _, match := range matches {
+			if match != path {
+				fmt.Println(match)
+				found = true
+			}
+		}
+	}
+	finish(found)
+}
+
+// contains lists the given files whose content is indexed, e.g. to check
+// whether a file outside the scanned roots is stored somewhere already.
+func contains(args []string) {
+	options := parseQueryOptions("contains", "file ...", args)
+	store = openExistingStore(options.db)
+	settings := querySettings()
+
+	found := false
+	for _, file := range options.args {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			log.Panic(err)
+		}
+		matches, err := indexedCopies(settings, path)
+		if err != nil {
+			log.Printf("lookup of '%s': %v", file, err)
+			continue
+		}
+		if len(matches) > 0 {
+			fmt.Printf("%s: %s\n", file, strings.Join(matches, ", "))
+			found = true
+		}
+	}
+	finish(found)
+}
+
+// sizeRange lists the indexed files in a size range with their size.
+func sizeRange(args []string) {
+	options := parseQueryOptions("size", "", args)
+	store = openExistingStore(options.db)
+
+	query := bh.Where("Size").Ge(int64(options.minSize))
+	if options.maxSize > 0 {
+		query = query.And("Size").Le(int64(options.maxSize))
+	}
+	entries := []FileEntry{}
+	if err := store.Find(&entries, (*bh.Query)(query.Index("Size"))); err != nil {
+		log.Panic(err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	for _, entry := range entries {
+		fmt.Printf("%d\t%s\n", entry.Size, entry.ID)
+	}
+	finish(len(entries) > 0)
+}
+
+func querySettings() Settings {
+	settings, err := readSettings()
+	if err != nil {
+		log.Panic(err)
+	}
+	return settings
+}
+
+// indexedCopies returns the indexed files with the content of path. Files of
+// the same size are compared by their full hashes, files without one byte for
+// byte.
+func indexedCopies(settings Settings, path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("no regular file")
+	}
+
+	candidates := []FileEntry{}
+	if err := store.Find(&candidates, (*bh.Query)(bh.Where("Size").Eq(info.Size()).Index("Size"))); err != nil {
+		return nil, err
+	}
+
+	var digest FileEntry
+	matches := []string{}
+	for _, candidate := range candidates {
+		if candidate.ID == path {
+			matches = append(matches, path)
+			continue
+		}
+		if candidate.Hash == "" {
+			same, err := sameContent(path, candidate.ID)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			if same {
+				matches = append(matches, candidate.ID)
+			}
+			continue
+		}
+		if digest.Hash == "" {
+			if digest, _, err = fullHash(context.Background(), FileEntry{ID: path}, settings.hashers()); err != nil {
+				return nil, err
+			}
+		}
+		if candidate.Hash == digest.Hash && candidate.Strong == digest.Strong {
+			matches = append(matches, candidate.ID)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink creates dst sharing the data blocks of src with the FICLONE ioctl.
+// It fails on filesystems without copy on write support like ext4.
+func reflink(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlFileClone(int(out.Fd()), int(in.Fd())); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("reflink: %w", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+func reflink(src, dst string, mode fs.FileMode) error {
+	return errors.New("reflink is only supported on linux")
+}
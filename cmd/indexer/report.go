@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/fiurthorn/go/lib"
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+// duplicateGroup is a set of files with identical content. Hard links to the
+// same data count as one copy. Total is the size of all copies, Wasted the
+// size of all copies but one.
+type duplicateGroup struct {
+	Hash   string   `json:"hash"`
+	Strong string   `json:"strong,omitempty"`
+	Size   int64    `json:"size"`
+	Count  int      `json:"count"`
+	Copies int      `json:"copies"`
+	Total  int64    `json:"total"`
+	Wasted int64    `json:"wasted"`
+	Files  []string `json:"files"`
+}
+
+// duplicates loads the groups of at least two copies which pass the filter,
+// sorted by wasted space. Files which are all links to the same data waste no
+// space and are left out.
+func duplicates(filter func(entry FileEntry) bool) ([]duplicateGroup, error) {
+	results := []HashEntry{}
+	err := store.Find(&results, (*bh.Query)(bh.Where("Files").MatchFunc(func(ra *bh.RecordAccess) (bool, error) {
+		if files, ok := ra.Field().(*lib.StringSet); ok {
+			return files.Len() > 1, nil
+		}
+		return false, nil
+	})))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []duplicateGroup{}
+	for _, result := range results {
+		group := duplicateGroup{Hash: result.Hash, Strong: result.Strong, Files: []string{}}
+		inodes := map[[2]uint64]bool{}
+		for _, file := range result.Files.SortedValues() {
+			var entry FileEntry
+			if err := store.Get(file, &entry); err != nil {
+				log.Printf("file '%s': %v", file, err)
+				continue
+			}
+			if filter(entry) {
+				group.Size = entry.Size
+				group.Files = append(group.Files, file)
+				if entry.Inode == 0 {
+					group.Copies++
+				} else if id := [2]uint64{entry.Device, entry.Inode}; !inodes[id] {
+					inodes[id] = true
+					group.Copies++
+				}
+			}
+		}
+		if group.Copies < 2 {
+			continue
+		}
+		group.Count = len(group.Files)
+		group.Total = group.Size * int64(group.Copies)
+		group.Wasted = group.Size * int64(group.Copies-1)
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Wasted != groups[j].Wasted {
+			return groups[i].Wasted > groups[j].Wasted
+		}
+		return groups[i].Files[0] < groups[j].Files[0]
+	})
+	return groups, nil
+}
+
+// duplicateReport lists the identical and similar directories, the
+// duplicate files outside of the redundant directory copies and the files the
+// scans could not read.
+type duplicateReport struct {
+	Directories []directoryGroup     `json:"directories"`
+	Similar     []similarDirectories `json:"similar"`
+	Files       []duplicateGroup     `json:"files"`
+	Errors      []ScanError          `json:"errors"`
+}
+
+func report(args []string) {
+	options := parseReportOptions(args)
+
+	store = openExistingStore(options.db)
+	defer store.Close()
+
+	selected := func(path string, size int64) bool {
+		return size >= int64(options.minSize) && (len(options.prefixes) == 0 || below(options.prefixes, path))
+	}
+
+	result := duplicateReport{Directories: []directoryGroup{}, Similar: []similarDirectories{}}
+	redundant := []string{}
+	if options.dirs {
+		entries := []FileEntry{}
+		if err := store.Find(&entries, &bh.Query{}); err != nil {
+			log.Panic(err)
+		}
+		groups, similar := duplicateDirectories(entries, options.similar, selected)
+		result.Directories = groups
+		if similar != nil {
+			result.Similar = similar
+		}
+		// the copies of a directory but the first are covered by its group
+		for _, group := range groups {
+			redundant = append(redundant, group.Directories[1:]...)
+		}
+	}
+
+	groups, err := duplicates(func(entry FileEntry) bool {
+		return selected(entry.ID, entry.Size) && !below(redundant, entry.ID)
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+	result.Files = groups
+
+	result.Errors, err = loadErrors(func(path string) bool {
+		return len(options.prefixes) == 0 || below(options.prefixes, path)
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	switch options.format {
+	case formatJSON:
+		err = writeJSON(out, result)
+	case formatCSV:
+		err = writeCSV(out, result)
+	default:
+		err = writeHuman(out, result)
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+func writeJSON(w io.Writer, result duplicateReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// writeCSV writes one record per file or directory, group numbers the
+// groups of each kind.
+func writeCSV(w io.Writer, result duplicateReport) error {
+	records := csv.NewWriter(w)
+	records.Write([]string{"kind", "group", "hash", "strong", "size", "count", "copies", "total", "wasted", "similarity", "path", "error"})
+	for i, group := range result.Directories {
+		for _, dir := range group.Directories {
+			records.Write([]string{
+				"directory",
+				strconv.Itoa(i + 1),
+				group.Hash,
+				"",
+				strconv.FormatInt(group.Size, 10),
+				strconv.Itoa(group.Count),
+				strconv.Itoa(group.Count),
+				strconv.FormatInt(group.Total, 10),
+				strconv.FormatInt(group.Wasted, 10),
+				"100",
+				dir,
+				"",
+			})
+		}
+	}
+	for i, pair := range result.Similar {
+		for k, dir := range pair.Directories {
+			records.Write([]string{
+				"similar",
+				strconv.Itoa(i + 1),
+				"",
+				"",
+				strconv.FormatInt(pair.Sizes[k], 10),
+				"2",
+				"",
+				"",
+				"",
+				strconv.FormatFloat(pair.Similarity, 'f', 1, 64),
+				dir,
+				"",
+			})
+		}
+	}
+	for i, group := range result.Files {
+		for _, file := range group.Files {
+			records.Write([]string{
+				"file",
+				strconv.Itoa(i + 1),
+				group.Hash,
+				group.Strong,
+				strconv.FormatInt(group.Size, 10),
+				strconv.Itoa(group.Count),
+				strconv.Itoa(group.Copies),
+				strconv.FormatInt(group.Total, 10),
+				strconv.FormatInt(group.Wasted, 10),
+				"",
+				file,
+				"",
+			})
+		}
+	}
+	for _, entry := range result.Errors {
+		records.Write([]string{"error", "", "", "", "", "", "", "", "", "", entry.ID, entry.Kind + ": " + entry.Message})
+	}
+	records.Flush()
+	return records.Error()
+}
+
+func writeHuman(w io.Writer, result duplicateReport) error {
+	wasted := int64(0)
+	for _, group := range result.Directories {
+		fmt.Fprintf(w, "%s wasted, %d x %s directory of %d files, %s\n", humanSize(group.Wasted), group.Count, humanSize(group.Size), group.Files, group.Hash)
+		for _, dir := range group.Directories {
+			fmt.Fprintf(w, "  %s%c\n", dir, filepath.Separator)
+		}
+		fmt.Fprintln(w)
+		wasted += group.Wasted
+	}
+	for _, pair := range result.Similar {
+		fmt.Fprintf(w, "%.1f%% similar, %s shared\n", pair.Similarity, humanSize(pair.Shared))
+		for k, dir := range pair.Directories {
+			fmt.Fprintf(w, "  %s%c (%s)\n", dir, filepath.Separator, humanSize(pair.Sizes[k]))
+		}
+		fmt.Fprintln(w)
+	}
+
+	files := 0
+	for _, group := range result.Files {
+		fmt.Fprintf(w, "%s wasted, %d x %s = %s, %d files, %s\n", humanSize(group.Wasted), group.Copies, humanSize(group.Size), humanSize(group.Total), group.Count, group.Hash)
+		for _, file := range group.Files {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+		fmt.Fprintln(w)
+		files += group.Count
+		wasted += group.Wasted
+	}
+	for _, entry := range result.Errors {
+		fmt.Fprintf(w, "%-10s %s: %s\n", entry.Kind, entry.ID, entry.Message)
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintf(w, "%d directory groups, %d similar directories, %d file groups, %d files, %s wasted, %d errors\n",
+		len(result.Directories), len(result.Similar), len(result.Files), files, humanSize(wasted), len(result.Errors))
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicatesLinks(t *testing.T) {
+	openTestStore(t)
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	link := func(name, target string) string {
+		path := filepath.Join(dir, name)
+		if err := os.Link(target, path); err != nil {
+			t.Skip(err)
+		}
+		return path
+	}
+	add := func(path, hash string) {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := newFileEntry(path, info)
+		entry.Hash = hash
+		index(entry)
+	}
+
+	// only links to one file
+	linked := write("linked", "linked")
+	add(linked, "linked")
+	add(link("linked2", linked), "linked")
+	// a copy and a link to it
+	copy1 := write("copy1", "copy")
+	add(copy1, "copy")
+	add(link("copy1b", copy1), "copy")
+	add(write("copy2", "copy"), "copy")
+
+	groups, err := duplicates(func(entry FileEntry) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("groups %+v", groups)
+	}
+	group := groups[0]
+	if group.Hash != "copy" || group.Count != 3 || group.Copies != 2 || group.Wasted != 4 {
+		t.Errorf("group %+v", group)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fiurthorn/go/lib"
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+type HashEntry struct {
+	ID     uint64 `badgerhold:"key" boltholdKey:"ID"`
+	Hash   string `badgerhold:"index" boltholdIndex:"Hash"`
+	Strong string
+
+	Files *lib.StringSet
+}
+
+// FileEntry remembers the state of a file when it was indexed. The hashes are
+// reused as long as size, modification time, device and inode are unchanged.
+// Partial and Hash stay empty until another file of the same size, or with
+// the same partial hash, requires them.
+type FileEntry struct {
+	ID   string `badgerhold:"key" boltholdKey:"ID"`
+	Size int64  `badgerhold:"index" boltholdIndex:"Size"`
+
+	ModTime time.Time
+	Device  uint64
+	Inode   uint64
+	Partial string
+	Hash    string
+	Strong  string
+}
+
+func newFileEntry(path string, info fs.FileInfo) FileEntry {
+	device, inode := fileID(info)
+	return FileEntry{
+		ID:      path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Device:  device,
+		Inode:   inode,
+	}
+}
+
+// unchanged reports whether the file still matches the indexed state. Entries
+// of older databases carry no modification time and are always indexed again.
+func (e *FileEntry) unchanged(info fs.FileInfo) bool {
+	device, inode := fileID(info)
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) &&
+		e.Device == device && e.Inode == inode
+}
+
+// legacyHashEntry is a HashEntry as written before lib.StringSet had its own
+// gob encoding, Files was stored as a plain map.
+type legacyHashEntry struct {
+	ID     uint64
+	Hash   string
+	Strong string
+	Files  map[string]int
+}
+
+// decode reads the records with gob. Hash entries of older databases are read
+// with their plain map of files and stored in the current encoding when they
+// are updated.
+func decode(data []byte, value interface{}) error {
+	err := bh.DefaultDecode(data, value)
+	entry, ok := value.(*HashEntry)
+	if err == nil || !ok {
+		return err
+	}
+	var legacy legacyHashEntry
+	if bh.DefaultDecode(data, &legacy) != nil {
+		return err
+	}
+	files := lib.NewStringSet()
+	for file := range legacy.Files {
+		files.Add(file)
+	}
+	*entry = HashEntry{ID: legacy.ID, Hash: legacy.Hash, Strong: legacy.Strong, Files: files}
+	return nil
+}
+
+func openStore(dir string) *bh.Store {
+	// //badger
+	options := bh.DefaultOptions
+	options.Dir = dir
+	options.ValueDir = dir
+	options.Decoder = decode
+	store, err := bh.Open(options)
+
+	// bolt
+	// store, err = bh.Open(filepath.Join(dir, "bold.db"), 0666, &bh.Options{})
+
+	if err != nil {
+		log.Panic(err)
+	}
+	return store
+}
+
+// openExistingStore opens the database in dir for the commands which work on
+// the results of a scan, it fails instead of creating a new one.
+func openExistingStore(dir string) *bh.Store {
+	// badger writes the MANIFEST when it creates a database
+	if _, err := os.Stat(filepath.Join(dir, "MANIFEST")); err != nil {
+		log.Fatalf("no database in '%s': %v", dir, err)
+	}
+	return openStore(dir)
+}
+
+func hashQuery(entry FileEntry) *bh.Query {
+	return (*bh.Query)(bh.Where("Hash").Eq(entry.Hash).And("Strong").Eq(entry.Strong).Index("Hash"))
+}
+
+// index stores the hash of a file and moves the file out of the group of its
+// previous hash.
+func index(entry FileEntry) {
+	var old FileEntry
+	if err := store.Get(entry.ID, &old); err == nil && (old.Hash != entry.Hash || old.Strong != entry.Strong) {
+		unlink(old)
+	}
+
+	var result HashEntry
+	switch err := store.FindOne(&result, hashQuery(entry)); {
+	case errors.Is(err, bh.ErrNotFound):
+		err = store.Insert(bh.NextSequence(), HashEntry{
+			Hash:   entry.Hash,
+			Strong: entry.Strong,
+			Files:  lib.NewStringSetWith(entry.ID),
+		})
+		if err != nil {
+			log.Printf("insert hash of '%s': %v", entry.ID, err)
+		}
+	case err != nil:
+		log.Printf("find hash of '%s': %v", entry.ID, err)
+	case !result.Files.Has(entry.ID):
+		result.Files.Add(entry.ID)
+		if err := store.Update(result.ID, result); err != nil {
+			log.Printf("update hash of '%s': %v", entry.ID, err)
+		}
+	}
+
+	if err := store.Upsert(entry.ID, entry); err != nil {
+		log.Printf("store file '%s': %v", entry.ID, err)
+	}
+}
+
+// unlink removes the file of entry from the group of its hash and drops the
+// group once it is empty. Entries without a hash belong to no group, those of
+// older databases got theirs from migrate.
+func unlink(entry FileEntry) {
+	if entry.Hash == "" {
+		return
+	}
+	file := entry.ID
+	query := hashQuery(entry)
+
+	results := []HashEntry{}
+	if err := store.Find(&results, query); err != nil {
+		log.Printf("find hash of '%s': %v", file, err)
+		return
+	}
+	for _, result := range results {
+		result.Files.Remove(file)
+		var err error
+		if result.Files.Len() == 0 {
+			err = store.Delete(result.ID, HashEntry{})
+		} else {
+			err = store.Update(result.ID, result)
+		}
+		if err != nil {
+			log.Printf("unlink '%s': %v", file, err)
+		}
+	}
+}
+
+// migrate copies the hashes of the groups into the file entries of an older
+// database, which only recorded the size of a file. It runs once, when the
+// settings of the database are recorded.
+func migrate() error {
+	results := []HashEntry{}
+	if err := store.Find(&results, &bh.Query{}); err != nil {
+		return err
+	}
+	migrated := 0
+	for _, result := range results {
+		for _, file := range result.Files.Values() {
+			var entry FileEntry
+			if err := store.Get(file, &entry); err != nil || entry.Hash != "" || !entry.ModTime.IsZero() {
+				continue
+			}
+			entry.ID, entry.Hash, entry.Strong = file, result.Hash, result.Strong
+			if err := store.Update(file, entry); err != nil {
+				return err
+			}
+			migrated++
+		}
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d files of an older database", migrated)
+	}
+	return nil
+}
+
+// prune removes the files below roots which were not seen by the scan and no
+// longer exist. Files which still exist but were skipped, e.g. by changed
+// filters, stay indexed.
+func prune(roots []string, seen *lib.StringSet) int {
+	entries := []FileEntry{}
+	if err := store.Find(&entries, &bh.Query{}); err != nil {
+		log.Printf("prune: %v", err)
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if seen.Has(entry.ID) || !below(roots, entry.ID) {
+			continue
+		}
+		if _, err := os.Lstat(entry.ID); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if forget(entry) {
+			log.Printf("removed %s", entry.ID)
+			removed++
+		}
+	}
+	return removed
+}
+
+// forget removes a file from its hash group and from the database.
+func forget(entry FileEntry) bool {
+	unlink(entry)
+	if err := store.Delete(entry.ID, FileEntry{}); err != nil {
+		log.Printf("remove file '%s': %v", entry.ID, err)
+		return false
+	}
+	return true
+}
+
+func below(roots []string, path string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fiurthorn/go/lib"
+	bh "github.com/timshannon/badgerhold/v4"
+)
+
+// baselineSet and baselineHashEntry mirror the encoding of databases written
+// before lib.StringSet had its own gob encoding.
+type baselineSet map[string]int
+
+type baselineHashEntry struct {
+	ID    uint64
+	Hash  string
+	Files *baselineSet
+}
+
+func TestDecodeLegacyHashEntry(t *testing.T) {
+	files := baselineSet{"/a": 0, "/b": 0}
+	data, err := bh.DefaultEncode(baselineHashEntry{ID: 3, Hash: "abc", Files: &files})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry HashEntry
+	if err := bh.DefaultDecode(data, &entry); err == nil {
+		t.Fatal("legacy entry decoded without fallback")
+	}
+	if err := decode(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID != 3 || entry.Hash != "abc" || entry.Files.Len() != 2 || !entry.Files.Has("/a") || !entry.Files.Has("/b") {
+		t.Errorf("decoded %+v %v", entry, entry.Files)
+	}
+}
+
+func TestDecodeHashEntry(t *testing.T) {
+	entry := HashEntry{ID: 1, Hash: "abc", Strong: "def", Files: lib.NewStringSetWith("/a")}
+	data, err := bh.DefaultEncode(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded HashEntry
+	if err := decode(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != 1 || decoded.Hash != "abc" || decoded.Strong != "def" || !decoded.Files.Has("/a") {
+		t.Errorf("decoded %+v", decoded)
+	}
+}
+
+func TestDecodeOtherErrors(t *testing.T) {
+	var entry FileEntry
+	if err := decode([]byte("garbage"), &entry); err == nil {
+		t.Error("garbage decoded")
+	}
+	var hashEntry HashEntry
+	if err := decode([]byte("garbage"), &hashEntry); err == nil {
+		t.Error("garbage decoded as hash entry")
+	}
+}
+
+// openTestStore opens an empty database as the store of the commands.
+func openTestStore(t *testing.T) {
+	store = openStore(t.TempDir())
+	t.Cleanup(func() {
+		store.Close()
+		store = nil
+	})
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	openTestStore(t)
+	// an older database only recorded the size of the files
+	groups := map[string][]string{"aaa": {"/a", "/b"}, "bbb": {"/c"}}
+	for hash, files := range groups {
+		if err := store.Insert(bh.NextSequence(), HashEntry{Hash: hash, Files: lib.NewStringSetWith(files...)}); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if err := store.Insert(file, FileEntry{Size: 1}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := loadSettings("", ""); err != nil {
+		t.Fatal(err)
+	}
+	for hash, files := range groups {
+		for _, file := range files {
+			var entry FileEntry
+			if err := store.Get(file, &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry.ID != file || entry.Hash != hash || entry.Size != 1 {
+				t.Errorf("migrated %+v, want hash %s", entry, hash)
+			}
+		}
+	}
+
+	// unlink finds the group by the migrated hash
+	for _, file := range []string{"/a", "/c"} {
+		var entry FileEntry
+		if err := store.Get(file, &entry); err != nil {
+			t.Fatal(err)
+		}
+		unlink(entry)
+	}
+	results := []HashEntry{}
+	if err := store.Find(&results, &bh.Query{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Hash != "aaa" || results[0].Files.Len() != 1 || !results[0].Files.Has("/b") {
+		t.Errorf("groups after unlink %+v", results)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type walker struct {
+	ctx     context.Context
+	options *scanOptions
+	root    string
+	// after is the last file visited by an interrupted walk, the walk skips
+	// everything up to it
+	after string
+	visit func(path string, info fs.FileInfo)
+	fail  func(op, path string, err error)
+}
+
+// walk visits the accepted regular files below root in lexical order, after
+// the file after if it is not empty. The root itself is followed even if it is
+// a symbolic link. Directories and files which cannot be read are passed to
+// fail and skipped, only an unreadable root ends the walk with an error. The
+// walk stops when ctx is done.
+func walk(ctx context.Context, options *scanOptions, root, after string, visit func(path string, info fs.FileInfo), fail func(op, path string, err error)) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if after == "" && info.Mode().IsRegular() && options.accept(filepath.Base(root), info.Size()) {
+			visit(root, info)
+		}
+		return nil
+	}
+
+	w := &walker{ctx: ctx, options: options, root: root, after: after, visit: visit, fail: fail}
+	w.dir(root, 0, []fs.FileInfo{info})
+	return nil
+}
+
+// walkRoots walks the roots from the root and position of state on. visit
+// has to record the visited files as the position of state.
+func walkRoots(ctx context.Context, options *scanOptions, state *ScanState, visit func(path string, info fs.FileInfo), fail func(op, path string, err error)) {
+	for ; state.Root < len(options.roots); state.Root++ {
+		root := options.roots[state.Root]
+		if err := walk(ctx, options, root, state.Position, visit, fail); err != nil {
+			fail("walk", root, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		state.Position = ""
+	}
+}
+
+// dir walks the entries of dir which are depth levels below the root.
+// ancestors are the directories leading to dir to detect symlink cycles.
+func (w *walker) dir(dir string, depth int, ancestors []fs.FileInfo) {
+	if w.options.maxDepth >= 0 && depth > w.options.maxDepth {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// the entries read before the error are still walked
+		w.fail("read dir", dir, err)
+	}
+
+	for _, entry := range entries {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if w.after != "" && w.skip(path) {
+			continue
+		}
+		rel, _ := filepath.Rel(w.root, path)
+		if w.options.exclude.match(rel) {
+			continue
+		}
+
+		var info fs.FileInfo
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if w.options.symlinks == symlinksSkip {
+				continue
+			}
+			if info, err = os.Stat(path); err != nil {
+				w.fail("follow link", path, err)
+				continue
+			}
+			if info.IsDir() && w.options.symlinks != symlinksAll {
+				continue
+			}
+		} else if info, err = entry.Info(); err != nil {
+			w.fail("stat", path, err)
+			continue
+		}
+
+		switch {
+		case info.IsDir():
+			if cycle(ancestors, info) {
+				log.Printf("skip dir '%s': symlink cycle", path)
+				continue
+			}
+			w.dir(path, depth+1, append(ancestors, info))
+		case info.Mode().IsRegular():
+			if w.options.accept(rel, info.Size()) {
+				w.visit(path, info)
+			}
+		}
+	}
+}
+
+// skip reports whether path was walked before the interruption. Directories
+// leading to the last visited file are walked again to get to it.
+func (w *walker) skip(path string) bool {
+	if strings.HasPrefix(w.after, path+string(filepath.Separator)) {
+		return false
+	}
+	if walkOrder(path, w.after) <= 0 {
+		return true
+	}
+	// the walk is past the interruption
+	w.after = ""
+	return false
+}
+
+func cycle(ancestors []fs.FileInfo, info fs.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if os.SameFile(ancestor, info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fiurthorn/go/lib"
+)
+
+func TestWalkOrder(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"a", "a", 0},
+		{"a", "b", -1},
+		{"b", "a", 1},
+		// a plain string comparison puts the separator after the space
+		{"a", "a b", -1},
+		{"a" + sep + "z", "a b", -1},
+		{"a b", "a" + sep + "z", 1},
+		{"a" + sep + "z", "a.txt", -1},
+		{"a" + sep + "b", "a" + sep + "b" + sep + "c", -1},
+		{"a" + sep + "b" + sep + "c", "a" + sep + "b", 1},
+		{"a" + sep + "c", "a" + sep + "b" + sep + "c", 1},
+		{"a" + sep + "b" + sep + "c", "a" + sep + "c", -1},
+		{sep + "r" + sep + "x", sep + "r" + sep + "y", -1},
+	}
+	for _, test := range tests {
+		if got := walkOrder(test.a, test.b); sign(got) != test.want {
+			t.Errorf("walkOrder(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestWalkerSkip(t *testing.T) {
+	after := filepath.FromSlash("/r/a/b/f")
+	tests := []struct {
+		path string
+		skip bool
+		// passed reports whether the walk is past after
+		passed bool
+	}{
+		// directories leading to after are walked again
+		{"/r/a", false, false},
+		{"/r/a/b", false, false},
+		{"/r/a/a", true, false},
+		{"/r/a/b/e", true, false},
+		{"/r/a/b/f", true, false},
+		// a sibling whose name sorts between a and a/ as a string
+		{"/r/a b", false, true},
+		{"/r/a/b/g", false, true},
+		{"/r/a/c", false, true},
+		{"/r/b", false, true},
+	}
+	for _, test := range tests {
+		w := &walker{root: filepath.FromSlash("/r"), after: after}
+		path := filepath.FromSlash(test.path)
+		if got := w.skip(path); got != test.skip {
+			t.Errorf("skip(%q) after %q = %v, want %v", path, after, got, test.skip)
+		}
+		if passed := w.after == ""; passed != test.passed {
+			t.Errorf("skip(%q) after %q passed %v, want %v", path, after, passed, test.passed)
+		}
+	}
+}
+
+// makeTree creates the files below dir, names sort differently as plain
+// strings and per path component.
+func makeTree(t *testing.T, dir string, files ...string) {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var treeFiles = []string{"a/b/c", "a/b/d", "a/e", "a b", "a.txt", "b/x/y/z", "b/y", "c"}
+
+func walkAll(t *testing.T, ctx context.Context, options *scanOptions, root, after string) []string {
+	visited := []string{}
+	err := walk(ctx, options, root, after, func(path string, info fs.FileInfo) {
+		visited = append(visited, path)
+	}, func(op, path string, err error) {
+		t.Errorf("%s %s: %v", op, path, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return visited
+}
+
+func TestWalkResume(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, treeFiles...)
+	options := parseScanOptions([]string{root})
+
+	all := walkAll(t, context.Background(), options, root, "")
+	if len(all) != len(treeFiles) {
+		t.Fatalf("walked %v", all)
+	}
+	for i := 1; i < len(all); i++ {
+		if walkOrder(all[i-1], all[i]) >= 0 {
+			t.Errorf("walk visits %q before %q", all[i-1], all[i])
+		}
+	}
+
+	// resuming after every file visits exactly the files after it
+	for i, after := range all {
+		rest := walkAll(t, context.Background(), options, root, after)
+		if want := all[i+1:]; !reflect.DeepEqual(rest, want) {
+			t.Errorf("after %q walked %v, want %v", after, rest, want)
+		}
+	}
+}
+
+func TestWalkRootsResume(t *testing.T) {
+	base := t.TempDir()
+	first, last := filepath.Join(base, "first"), filepath.Join(base, "last")
+	makeTree(t, first, treeFiles...)
+	makeTree(t, last, treeFiles...)
+	options := parseScanOptions([]string{first, last})
+	fail := func(op, path string, err error) {
+		t.Errorf("%s %s: %v", op, path, err)
+	}
+
+	var all []string
+	state := &ScanState{}
+	walkRoots(context.Background(), options, state, func(path string, info fs.FileInfo) {
+		all = append(all, path)
+		state.Position = path
+	}, fail)
+	if len(all) != 2*len(treeFiles) || state.Root != 2 || state.Position != "" {
+		t.Fatalf("walked %d files, state %+v", len(all), state)
+	}
+
+	// interrupt after n files and resume from the saved state
+	for n := 1; n <= len(all); n++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		state := &ScanState{}
+		visited := []string{}
+		visit := func(path string, info fs.FileInfo) {
+			visited = append(visited, path)
+			state.Position = path
+			if len(visited) == n {
+				cancel()
+			}
+		}
+		walkRoots(ctx, options, state, visit, fail)
+		cancel()
+		if n < len(all) && state.Root >= len(options.roots) {
+			t.Fatalf("interrupted after %d files with state %+v", n, state)
+		}
+
+		walkRoots(context.Background(), options, state, visit, fail)
+		if !reflect.DeepEqual(visited, all) {
+			t.Errorf("interrupted after %d files, walked %v", n, visited)
+		}
+		if state.Root != len(options.roots) || state.Position != "" {
+			t.Errorf("interrupted after %d files, final state %+v", n, state)
+		}
+	}
+
+	// a completed walk resumes with nothing
+	state.Root, state.Position = len(options.roots), ""
+	walkRoots(context.Background(), options, state, func(path string, info fs.FileInfo) {
+		t.Errorf("visited %q after the last root", path)
+	}, fail)
+}
+
+func TestWalkFileRootResume(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(root, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options := parseScanOptions([]string{root})
+	if visited := walkAll(t, context.Background(), options, root, ""); len(visited) != 1 {
+		t.Errorf("walked %v", visited)
+	}
+	if visited := walkAll(t, context.Background(), options, root, root); len(visited) != 0 {
+		t.Errorf("resumed after the root, walked %v", visited)
+	}
+}
+
+func TestResumeKeepsErrors(t *testing.T) {
+	openTestStore(t)
+	t.Cleanup(func() {
+		failures = &scanErrors{paths: lib.NewStringSet(), counts: map[string]int{}}
+	})
+	root := t.TempDir()
+	makeTree(t, root, treeFiles...)
+	broken := filepath.Join(root, "a", "broken")
+	if err := os.Symlink(filepath.Join(root, "missing"), broken); err != nil {
+		t.Skip(err)
+	}
+	options := parseScanOptions([]string{"-symlinks", "files", root})
+
+	// an older error below the root which does not occur anymore
+	failures.record("stat", filepath.Join(root, "a", "b", "c"), fs.ErrPermission)
+	failures = &scanErrors{paths: lib.NewStringSet(), counts: map[string]int{}}
+
+	// interrupt the scan once it is past the broken link
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := newProgress(options)
+	walkRoots(ctx, options, &progress.state, func(path string, info fs.FileInfo) {
+		progress.visited(path)
+		if walkOrder(path, broken) > 0 {
+			cancel()
+		}
+	}, failures.record)
+	cancel()
+	progress.save()
+	if failures.Len() != 1 || progress.state.Root != 0 {
+		t.Fatalf("interrupted with %v, state %+v", failures, progress.state)
+	}
+
+	// resume in a new process
+	failures = &scanErrors{paths: lib.NewStringSet(), counts: map[string]int{}}
+	resumed, err := loadProgress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := failures.resume(options.roots, resumed.state.Start); err != nil {
+		t.Fatal(err)
+	}
+	walkRoots(context.Background(), options, &resumed.state, func(path string, info fs.FileInfo) {
+		if walkOrder(path, broken) <= 0 {
+			t.Errorf("visited %q again", path)
+		}
+	}, failures.record)
+	failures.clear(options.roots)
+
+	if failures.Len() != 1 || failures.counts[errorVanished] != 1 {
+		t.Errorf("resumed with %v", failures)
+	}
+	stored, err := loadErrors(func(path string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != broken || stored[0].Op != "follow link" {
+		t.Errorf("stored errors %+v", stored)
+	}
+}
@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BiMap is a one-to-one relation between keys and values with lookups in both
+// directions.
+type BiMap[Key comparable, Value comparable] struct {
+	forward map[Key]Value
+	inverse map[Value]Key
+}
+
+func NewBiMap[Key comparable, Value comparable]() *BiMap[Key, Value] {
+	return &BiMap[Key, Value]{
+		forward: map[Key]Value{},
+		inverse: map[Value]Key{},
+	}
+}
+
+// Set relates key and value. Former relations of key or of value are removed
+// to keep the mapping one-to-one.
+func (m *BiMap[Key, Value]) Set(key Key, value Value) {
+	m.RemoveKey(key)
+	m.RemoveValue(value)
+	m.forward[key] = value
+	m.inverse[value] = key
+}
+
+func (m *BiMap[Key, Value]) Get(key Key) (Value, bool) {
+	value, ok := m.forward[key]
+	return value, ok
+}
+
+func (m *BiMap[Key, Value]) GetKey(value Value) (Key, bool) {
+	key, ok := m.inverse[value]
+	return key, ok
+}
+
+func (m *BiMap[Key, Value]) RemoveKey(key Key) {
+	if value, ok := m.forward[key]; ok {
+		delete(m.forward, key)
+		delete(m.inverse, value)
+	}
+}
+
+func (m *BiMap[Key, Value]) RemoveValue(value Value) {
+	if key, ok := m.inverse[value]; ok {
+		delete(m.inverse, value)
+		delete(m.forward, key)
+	}
+}
+
+func (m *BiMap[Key, Value]) Has(key Key) bool {
+	_, ok := m.forward[key]
+	return ok
+}
+
+func (m *BiMap[Key, Value]) HasValue(value Value) bool {
+	_, ok := m.inverse[value]
+	return ok
+}
+
+// Inverse returns a view with keys and values swapped. Both maps share their
+// content.
+func (m *BiMap[Key, Value]) Inverse() *BiMap[Value, Key] {
+	return &BiMap[Value, Key]{
+		forward: m.inverse,
+		inverse: m.forward,
+	}
+}
+
+func (m *BiMap[Key, Value]) Keys() []Key {
+	keys := make([]Key, 0, len(m.forward))
+	for k := range m.forward {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (m *BiMap[Key, Value]) Values() []Value {
+	values := make([]Value, 0, len(m.inverse))
+	for v := range m.inverse {
+		values = append(values, v)
+	}
+	return values
+}
+
+// ForEach calls fn for every pair until fn returns false.
+func (m *BiMap[Key, Value]) ForEach(fn func(key Key, value Value) bool) {
+	for k, v := range m.forward {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+func (m *BiMap[Key, Value]) Len() int {
+	return len(m.forward)
+}
+
+func (m *BiMap[Key, Value]) String() string {
+	values := []string{}
+	for k, v := range m.forward {
+		values = append(values, fmt.Sprintf("'%v':'%v'", k, v))
+	}
+	return fmt.Sprintf("BiMap[%s]", strings.Join(values, ", "))
+}
@@ -0,0 +1,158 @@
+package lib
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+var ErrIncompatible = errors.New("incompatible parameters")
+
+const bloomFilterVersion = 1
+
+// BloomFilter is a probabilistic StringSet. Has never misses an added value,
+// but may report values which were never added at a configurable rate. The
+// memory used is independent of the length of the values.
+type BloomFilter struct {
+	bits []uint64
+	// m is the number of bits, k the number of hash functions
+	m uint64
+	k uint64
+}
+
+// NewBloomFilter sizes a filter for n values with the given false positive
+// rate, e.g. 0.01 for one percent.
+func NewBloomFilter(n uint64, falsePositiveRate float64) *BloomFilter {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		panic(fmt.Sprintf("bloom filter false positive rate %v out of range (0, 1)", falsePositiveRate))
+	}
+	if n == 0 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	return NewBloomFilterWithSize(uint64(m), uint64(math.Max(k, 1)))
+}
+
+// NewBloomFilterWithSize creates a filter of m bits using k hash functions.
+func NewBloomFilterWithSize(m, k uint64) *BloomFilter {
+	if m == 0 || k == 0 {
+		panic("bloom filter needs at least one bit and one hash function")
+	}
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// locations derives the k bit positions of value by double hashing.
+func (f *BloomFilter) locations(value string, fn func(bit uint64) bool) bool {
+	h1 := xxhash.Sum64String(value)
+	h2 := bits.RotateLeft64(h1, 32) | 1
+	for i := uint64(0); i < f.k; i++ {
+		if !fn((h1 + i*h2) % f.m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BloomFilter) Add(value string) {
+	f.locations(value, func(bit uint64) bool {
+		f.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+}
+
+func (f *BloomFilter) Has(value string) bool {
+	return f.locations(value, func(bit uint64) bool {
+		return f.bits[bit/64]&(1<<(bit%64)) != 0
+	})
+}
+
+func (f *BloomFilter) setBits() uint64 {
+	count := 0
+	for _, word := range f.bits {
+		count += bits.OnesCount64(word)
+	}
+	return uint64(count)
+}
+
+// Len estimates the number of distinct values added from the fill ratio.
+func (f *BloomFilter) Len() uint64 {
+	set := f.setBits()
+	if set == f.m {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(-float64(f.m) / float64(f.k) * math.Log(1-float64(set)/float64(f.m))))
+}
+
+// FalsePositiveRate estimates the current false positive rate from the fill
+// ratio.
+func (f *BloomFilter) FalsePositiveRate() float64 {
+	return math.Pow(float64(f.setBits())/float64(f.m), float64(f.k))
+}
+
+// Union adds all values of other, both filters need the same size.
+func (f *BloomFilter) Union(other *BloomFilter) error {
+	if f.m != other.m || f.k != other.k {
+		return fmt.Errorf("bloom filter union %d/%d with %d/%d: %w", f.m, f.k, other.m, other.k, ErrIncompatible)
+	}
+	for i, word := range other.bits {
+		f.bits[i] |= word
+	}
+	return nil
+}
+
+func (f *BloomFilter) Reset() {
+	for i := range f.bits {
+		f.bits[i] = 0
+	}
+}
+
+func (f *BloomFilter) String() string {
+	return fmt.Sprintf("BloomFilter[bits=%d, hashes=%d, ~%d values]", f.m, f.k, f.Len())
+}
+
+// MarshalBinary writes a version byte, m and k as uvarints and the bit words
+// in little endian order.
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	buf := []byte{bloomFilterVersion}
+	buf = appendUvarint(buf, f.m)
+	buf = appendUvarint(buf, f.k)
+	for _, word := range f.bits {
+		var scratch [8]byte
+		binary.LittleEndian.PutUint64(scratch[:], word)
+		buf = append(buf, scratch[:]...)
+	}
+	return buf, nil
+}
+
+func (f *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != bloomFilterVersion {
+		return ErrInvalidEncoding
+	}
+	m, data, err := readUvarint(data[1:])
+	if err != nil {
+		return err
+	}
+	k, data, err := readUvarint(data)
+	if err != nil {
+		return err
+	}
+	// (m-1)/64+1 is the number of words without overflowing for huge m
+	if m == 0 || k == 0 || len(data)%8 != 0 || uint64(len(data)/8) != (m-1)/64+1 {
+		return ErrInvalidEncoding
+	}
+	words := make([]uint64, len(data)/8)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	f.bits, f.m, f.k = words, m, k
+	return nil
+}
@@ -0,0 +1,263 @@
+package lib
+
+import (
+	"fmt"
+	"time"
+)
+
+type CacheEvictionReason int
+
+const (
+	// CacheFull evicted the least recently used entry to make room
+	CacheFull CacheEvictionReason = iota
+	// CacheExpired dropped an entry after its time to live
+	CacheExpired
+	// CacheRemoved dropped an entry by Remove or Purge
+	CacheRemoved
+)
+
+func (r CacheEvictionReason) String() string {
+	switch r {
+	case CacheFull:
+		return "full"
+	case CacheExpired:
+		return "expired"
+	case CacheRemoved:
+		return "removed"
+	}
+	return fmt.Sprintf("CacheEvictionReason(%d)", int(r))
+}
+
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+}
+
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type cacheEntry[Key comparable, Value any] struct {
+	key     Key
+	value   Value
+	expires time.Time
+	// prev points to the more recently used entry
+	prev, next *cacheEntry[Key, Value]
+}
+
+// Cache holds at most capacity entries and evicts the least recently used one
+// when full. Entries may expire after a time to live, expired entries are
+// dropped when they are met. A Cache is not safe for concurrent use, see
+// ConcurrentCache.
+type Cache[Key comparable, Value any] struct {
+	capacity int
+	ttl      time.Duration
+	items    map[Key]*cacheEntry[Key, Value]
+	// head is the most recently used entry
+	head, tail *cacheEntry[Key, Value]
+	onEvict    func(key Key, value Value, reason CacheEvictionReason)
+	stats      CacheStats
+	now        func() time.Time
+}
+
+// NewCache creates a cache for capacity entries, a capacity of zero or less
+// means unbounded. Entries expire after ttl unless ttl is zero.
+func NewCache[Key comparable, Value any](capacity int, ttl time.Duration) *Cache[Key, Value] {
+	return &Cache[Key, Value]{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    map[Key]*cacheEntry[Key, Value]{},
+		now:      time.Now,
+	}
+}
+
+// OnEvict registers fn to be called for every entry leaving the cache.
+func (c *Cache[Key, Value]) OnEvict(fn func(key Key, value Value, reason CacheEvictionReason)) {
+	c.onEvict = fn
+}
+
+func (c *Cache[Key, Value]) unlink(e *cacheEntry[Key, Value]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+func (c *Cache[Key, Value]) pushFront(e *cacheEntry[Key, Value]) {
+	e.next = c.head
+	if c.head != nil {
+		c.head.prev = e
+	} else {
+		c.tail = e
+	}
+	c.head = e
+}
+
+func (c *Cache[Key, Value]) evict(e *cacheEntry[Key, Value], reason CacheEvictionReason) {
+	c.unlink(e)
+	delete(c.items, e.key)
+	switch reason {
+	case CacheFull:
+		c.stats.Evictions++
+	case CacheExpired:
+		c.stats.Expirations++
+	}
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value, reason)
+	}
+}
+
+func (c *Cache[Key, Value]) expired(e *cacheEntry[Key, Value]) bool {
+	return !e.expires.IsZero() && !c.now().Before(e.expires)
+}
+
+// lookup returns the live entry for key and drops it if it is expired.
+func (c *Cache[Key, Value]) lookup(key Key) *cacheEntry[Key, Value] {
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if c.expired(e) {
+		c.evict(e, CacheExpired)
+		return nil
+	}
+	return e
+}
+
+func (c *Cache[Key, Value]) Set(key Key, value Value) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value with its own time to live, zero means no expiry.
+func (c *Cache[Key, Value]) SetWithTTL(key Key, value Value, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if e, ok := c.items[key]; ok {
+		e.value, e.expires = value, expires
+		c.unlink(e)
+		c.pushFront(e)
+		return
+	}
+
+	e := &cacheEntry[Key, Value]{key: key, value: value, expires: expires}
+	c.items[key] = e
+	c.pushFront(e)
+	for c.capacity > 0 && len(c.items) > c.capacity {
+		if c.expired(c.tail) {
+			c.evict(c.tail, CacheExpired)
+		} else {
+			c.evict(c.tail, CacheFull)
+		}
+	}
+}
+
+// Get returns the value for key and marks it as recently used.
+func (c *Cache[Key, Value]) Get(key Key) (Value, bool) {
+	e := c.lookup(key)
+	if e == nil {
+		c.stats.Misses++
+		var zero Value
+		return zero, false
+	}
+	c.stats.Hits++
+	c.unlink(e)
+	c.pushFront(e)
+	return e.value, true
+}
+
+// GetOrLoad returns the cached value for key or stores and returns the value
+// from load. Errors of load are returned and not cached.
+func (c *Cache[Key, Value]) GetOrLoad(key Key, load func(key Key) (Value, error)) (Value, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	value, err := load(key)
+	if err != nil {
+		return value, err
+	}
+	c.Set(key, value)
+	return value, nil
+}
+
+// Peek returns the value for key without touching recency or statistics.
+func (c *Cache[Key, Value]) Peek(key Key) (Value, bool) {
+	if e, ok := c.items[key]; ok && !c.expired(e) {
+		return e.value, true
+	}
+	var zero Value
+	return zero, false
+}
+
+func (c *Cache[Key, Value]) Has(key Key) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+func (c *Cache[Key, Value]) Remove(key Key) bool {
+	e, ok := c.items[key]
+	if ok {
+		c.evict(e, CacheRemoved)
+	}
+	return ok
+}
+
+func (c *Cache[Key, Value]) Purge() {
+	for c.tail != nil {
+		c.evict(c.tail, CacheRemoved)
+	}
+}
+
+// RemoveExpired drops all expired entries and returns their number.
+func (c *Cache[Key, Value]) RemoveExpired() int {
+	count := 0
+	for e := c.head; e != nil; {
+		next := e.next
+		if c.expired(e) {
+			c.evict(e, CacheExpired)
+			count++
+		}
+		e = next
+	}
+	return count
+}
+
+// Keys returns the keys from the most to the least recently used one.
+func (c *Cache[Key, Value]) Keys() []Key {
+	keys := make([]Key, 0, len(c.items))
+	for e := c.head; e != nil; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// Len returns the number of entries including expired ones not yet dropped.
+func (c *Cache[Key, Value]) Len() int {
+	return len(c.items)
+}
+
+func (c *Cache[Key, Value]) Capacity() int {
+	return c.capacity
+}
+
+func (c *Cache[Key, Value]) Stats() CacheStats {
+	return c.stats
+}
+
+func (c *Cache[Key, Value]) ResetStats() {
+	c.stats = CacheStats{}
+}
@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// ConcurrentCache is a Cache safe for concurrent use. Every access changes
+// the recency order, so all operations take the same lock. Eviction callbacks
+// run while the lock is held and must not use the cache.
+type ConcurrentCache[Key comparable, Value any] struct {
+	mu    sync.Mutex
+	cache *Cache[Key, Value]
+}
+
+func NewConcurrentCache[Key comparable, Value any](capacity int, ttl time.Duration) *ConcurrentCache[Key, Value] {
+	return &ConcurrentCache[Key, Value]{cache: NewCache[Key, Value](capacity, ttl)}
+}
+
+func (c *ConcurrentCache[Key, Value]) OnEvict(fn func(key Key, value Value, reason CacheEvictionReason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.OnEvict(fn)
+}
+
+func (c *ConcurrentCache[Key, Value]) Set(key Key, value Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Set(key, value)
+}
+
+func (c *ConcurrentCache[Key, Value]) SetWithTTL(key Key, value Value, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.SetWithTTL(key, value, ttl)
+}
+
+func (c *ConcurrentCache[Key, Value]) Get(key Key) (Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Get(key)
+}
+
+// GetOrLoad runs load without holding the lock, so concurrent misses of the
+// same key may load it more than once.
+func (c *ConcurrentCache[Key, Value]) GetOrLoad(key Key, load func(key Key) (Value, error)) (Value, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	value, err := load(key)
+	if err != nil {
+		return value, err
+	}
+	c.Set(key, value)
+	return value, nil
+}
+
+func (c *ConcurrentCache[Key, Value]) Peek(key Key) (Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Peek(key)
+}
+
+func (c *ConcurrentCache[Key, Value]) Has(key Key) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Has(key)
+}
+
+func (c *ConcurrentCache[Key, Value]) Remove(key Key) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Remove(key)
+}
+
+func (c *ConcurrentCache[Key, Value]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Purge()
+}
+
+func (c *ConcurrentCache[Key, Value]) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.RemoveExpired()
+}
+
+func (c *ConcurrentCache[Key, Value]) Keys() []Key {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Keys()
+}
+
+func (c *ConcurrentCache[Key, Value]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Len()
+}
+
+func (c *ConcurrentCache[Key, Value]) Capacity() int {
+	return c.cache.Capacity()
+}
+
+func (c *ConcurrentCache[Key, Value]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Stats()
+}
+
+func (c *ConcurrentCache[Key, Value]) ResetStats() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.ResetStats()
+}
@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const shardCount = 32
+
+// shardIndex spreads keys over the shards with 32-bit FNV-1a.
+func shardIndex(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash % shardCount
+}
+
+type stringMapShard struct {
+	sync.RWMutex
+	items map[string]string
+}
+
+type ConcurrentStringMap struct {
+	shards [shardCount]*stringMapShard
+}
+
+func NewConcurrentStringMap() *ConcurrentStringMap {
+	m := &ConcurrentStringMap{}
+	for i := range m.shards {
+		m.shards[i] = &stringMapShard{items: map[string]string{}}
+	}
+	return m
+}
+
+func (m *ConcurrentStringMap) shard(key string) *stringMapShard {
+	return m.shards[shardIndex(key)]
+}
+
+func (m *ConcurrentStringMap) Remove(key string) {
+	shard := m.shard(key)
+	shard.Lock()
+	delete(shard.items, key)
+	shard.Unlock()
+}
+
+func (m *ConcurrentStringMap) Get(key string) (string, bool) {
+	shard := m.shard(key)
+	shard.RLock()
+	elem, ok := shard.items[key]
+	shard.RUnlock()
+	return elem, ok
+}
+
+func (m *ConcurrentStringMap) Set(key, value string) {
+	shard := m.shard(key)
+	shard.Lock()
+	shard.items[key] = value
+	shard.Unlock()
+}
+
+// GetOrSet returns the existing value for key if present. Otherwise it stores
+// and returns value. The loaded result reports whether the value was present.
+func (m *ConcurrentStringMap) GetOrSet(key, value string) (actual string, loaded bool) {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if elem, ok := shard.items[key]; ok {
+		return elem, true
+	}
+	shard.items[key] = value
+	return value, false
+}
+
+// Swap stores value for key and returns the previous value if any.
+func (m *ConcurrentStringMap) Swap(key, value string) (previous string, loaded bool) {
+	shard := m.shard(key)
+	shard.Lock()
+	previous, loaded = shard.items[key]
+	shard.items[key] = value
+	shard.Unlock()
+	return previous, loaded
+}
+
+// CompareAndSwap stores value for key only if the current value equals old.
+func (m *ConcurrentStringMap) CompareAndSwap(key, old, value string) bool {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if elem, ok := shard.items[key]; !ok || elem != old {
+		return false
+	}
+	shard.items[key] = value
+	return true
+}
+
+// Compute calls fn with the current value of key while holding the key's lock.
+// The returned value is stored if keep is true, otherwise the key is removed.
+func (m *ConcurrentStringMap) Compute(key string, fn func(value string, ok bool) (string, bool)) (string, bool) {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	elem, ok := shard.items[key]
+	value, keep := fn(elem, ok)
+	if keep {
+		shard.items[key] = value
+	} else {
+		delete(shard.items, key)
+	}
+	return value, keep
+}
+
+// ForEach calls fn for every entry until fn returns false. Each shard is read
+// locked while its entries are visited, so fn must not modify the map.
+func (m *ConcurrentStringMap) ForEach(fn func(key, value string) bool) {
+	for _, shard := range m.shards {
+		if !shard.forEach(fn) {
+			return
+		}
+	}
+}
+
+func (s *stringMapShard) forEach(fn func(key, value string) bool) bool {
+	s.RLock()
+	defer s.RUnlock()
+	for k, v := range s.items {
+		if !fn(k, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Iter returns an iterator over the entries in the shape of
+// iter.Seq2[string, string].
+func (m *ConcurrentStringMap) Iter() func(yield func(key, value string) bool) {
+	return m.ForEach
+}
+
+func (m *ConcurrentStringMap) Keys() []string {
+	keys := []string{}
+	for _, shard := range m.shards {
+		shard.RLock()
+		for k := range shard.items {
+			keys = append(keys, k)
+		}
+		shard.RUnlock()
+	}
+	return keys
+}
+
+func (m *ConcurrentStringMap) Values() []string {
+	values := []string{}
+	for _, shard := range m.shards {
+		shard.RLock()
+		for _, v := range shard.items {
+			values = append(values, v)
+		}
+		shard.RUnlock()
+	}
+	return values
+}
+
+func (m *ConcurrentStringMap) SortedKeys() []string {
+	keys := m.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot copies the content into a plain StringMap. Every shard is copied
+// consistently, but writes to other shards may happen during the copy.
+func (m *ConcurrentStringMap) Snapshot() *StringMap {
+	snapshot := NewStringMap()
+	for _, shard := range m.shards {
+		shard.RLock()
+		for k, v := range shard.items {
+			snapshot.Set(k, v)
+		}
+		shard.RUnlock()
+	}
+	return snapshot
+}
+
+func (m *ConcurrentStringMap) String() string {
+	values := []string{}
+	snapshot := m.Snapshot()
+	for _, k := range snapshot.SortedKeys() {
+		values = append(values, "'"+k+"':'"+(*snapshot)[k]+"'")
+	}
+	return fmt.Sprintf("ConcurrentStringMap[%s]", strings.Join(values, ", "))
+}
+
+func (m *ConcurrentStringMap) Len() int {
+	count := 0
+	for _, shard := range m.shards {
+		shard.RLock()
+		count += len(shard.items)
+		shard.RUnlock()
+	}
+	return count
+}
+
+func (m *ConcurrentStringMap) Has(value string) bool {
+	_, ok := m.Get(value)
+	return ok
+}
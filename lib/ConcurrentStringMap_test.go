@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"sync"
+	"testing"
+)
+
+// lockedStringMap is the plain StringMap behind a mutex, the baseline of the
+// sharded map.
+type lockedStringMap struct {
+	mu sync.RWMutex
+	m  *StringMap
+}
+
+func (s *lockedStringMap) Set(key, value string) {
+	s.mu.Lock()
+	s.m.Set(key, value)
+	s.mu.Unlock()
+}
+
+func (s *lockedStringMap) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Get(key)
+}
+
+func (s *lockedStringMap) Has(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Has(key)
+}
+
+func filledStringMaps(keys []string) (*ConcurrentStringMap, *lockedStringMap) {
+	concurrent, locked := NewConcurrentStringMap(), &lockedStringMap{m: NewStringMap()}
+	for _, key := range keys[:len(keys)/2] {
+		concurrent.Set(key, key)
+		locked.Set(key, key)
+	}
+	return concurrent, locked
+}
+
+func BenchmarkConcurrentStringMapSet(b *testing.B) {
+	keys := benchmarkKeyList()
+	m := NewConcurrentStringMap()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%benchmarkKeys]
+			m.Set(key, key)
+		}
+	})
+}
+
+func BenchmarkLockedStringMapSet(b *testing.B) {
+	keys := benchmarkKeyList()
+	m := &lockedStringMap{m: NewStringMap()}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%benchmarkKeys]
+			m.Set(key, key)
+		}
+	})
+}
+
+func BenchmarkConcurrentStringMapGet(b *testing.B) {
+	keys := benchmarkKeyList()
+	m, _ := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Get(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkLockedStringMapGet(b *testing.B) {
+	keys := benchmarkKeyList()
+	_, m := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Get(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkConcurrentStringMapHas(b *testing.B) {
+	keys := benchmarkKeyList()
+	m, _ := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Has(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkLockedStringMapHas(b *testing.B) {
+	keys := benchmarkKeyList()
+	_, m := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Has(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+// mixed workload, one Set for every seven Get
+func BenchmarkConcurrentStringMapMixed(b *testing.B) {
+	keys := benchmarkKeyList()
+	m, _ := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%benchmarkKeys]
+			if i%8 == 0 {
+				m.Set(key, key)
+			} else {
+				m.Get(key)
+			}
+		}
+	})
+}
+
+func BenchmarkLockedStringMapMixed(b *testing.B) {
+	keys := benchmarkKeyList()
+	_, m := filledStringMaps(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := keys[i%benchmarkKeys]
+			if i%8 == 0 {
+				m.Set(key, key)
+			} else {
+				m.Get(key)
+			}
+		}
+	})
+}
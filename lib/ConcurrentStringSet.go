@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type stringSetShard struct {
+	sync.RWMutex
+	items map[string]void
+}
+
+type ConcurrentStringSet struct {
+	shards [shardCount]*stringSetShard
+}
+
+func NewConcurrentStringSet() *ConcurrentStringSet {
+	s := &ConcurrentStringSet{}
+	for i := range s.shards {
+		s.shards[i] = &stringSetShard{items: map[string]void{}}
+	}
+	return s
+}
+
+func NewConcurrentStringSetWith(keys ...string) *ConcurrentStringSet {
+	s := NewConcurrentStringSet()
+
+	for _, key := range keys {
+		s.Add(key)
+	}
+
+	return s
+}
+
+func (m *ConcurrentStringSet) shard(key string) *stringSetShard {
+	return m.shards[shardIndex(key)]
+}
+
+func (m *ConcurrentStringSet) Remove(key string) {
+	shard := m.shard(key)
+	shard.Lock()
+	delete(shard.items, key)
+	shard.Unlock()
+}
+
+// RemoveIfPresent removes key and reports whether it was part of the set.
+func (m *ConcurrentStringSet) RemoveIfPresent(key string) bool {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.items[key]; !ok {
+		return false
+	}
+	delete(shard.items, key)
+	return true
+}
+
+func (m *ConcurrentStringSet) Add(key string) {
+	shard := m.shard(key)
+	shard.Lock()
+	shard.items[key] = empty
+	shard.Unlock()
+}
+
+// AddIfAbsent adds key and reports whether it was not yet part of the set.
+func (m *ConcurrentStringSet) AddIfAbsent(key string) bool {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.items[key]; ok {
+		return false
+	}
+	shard.items[key] = empty
+	return true
+}
+
+// ForEach calls fn for every value until fn returns false. Each shard is read
+// locked while its values are visited, so fn must not modify the set.
+func (m *ConcurrentStringSet) ForEach(fn func(value string) bool) {
+	for _, shard := range m.shards {
+		if !shard.forEach(fn) {
+			return
+		}
+	}
+}
+
+func (s *stringSetShard) forEach(fn func(value string) bool) bool {
+	s.RLock()
+	defer s.RUnlock()
+	for k := range s.items {
+		if !fn(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// Iter returns an iterator over the values in the shape of iter.Seq[string].
+func (m *ConcurrentStringSet) Iter() func(yield func(value string) bool) {
+	return m.ForEach
+}
+
+func (m *ConcurrentStringSet) Values() []string {
+	keys := []string{}
+	for _, shard := range m.shards {
+		shard.RLock()
+		for k := range shard.items {
+			keys = append(keys, k)
+		}
+		shard.RUnlock()
+	}
+	return keys
+}
+
+func (m *ConcurrentStringSet) SortedValues() []string {
+	values := m.Values()
+	sort.Strings(values)
+	return values
+}
+
+// Snapshot copies the content into a plain StringSet.
+func (m *ConcurrentStringSet) Snapshot() *StringSet {
+	return NewStringSetWith(m.Values()...)
+}
+
+func (m *ConcurrentStringSet) String() string {
+	return fmt.Sprintf("ConcurrentStringSet[%s]", strings.Join(m.SortedValues(), ", "))
+}
+
+func (m *ConcurrentStringSet) Len() int {
+	count := 0
+	for _, shard := range m.shards {
+		shard.RLock()
+		count += len(shard.items)
+		shard.RUnlock()
+	}
+	return count
+}
+
+func (m *ConcurrentStringSet) Has(value string) bool {
+	shard := m.shard(value)
+	shard.RLock()
+	_, ok := shard.items[value]
+	shard.RUnlock()
+	return ok
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const benchmarkKeys = 1 << 16
+
+func benchmarkKeyList() []string {
+	keys := make([]string, benchmarkKeys)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// lockedStringSet is the plain StringSet behind a mutex, the baseline of the
+// sharded set.
+type lockedStringSet struct {
+	mu  sync.RWMutex
+	set *StringSet
+}
+
+func (s *lockedStringSet) Add(key string) {
+	s.mu.Lock()
+	s.set.Add(key)
+	s.mu.Unlock()
+}
+
+func (s *lockedStringSet) Has(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set.Has(key)
+}
+
+func BenchmarkConcurrentStringSetAdd(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := NewConcurrentStringSet()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Add(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkLockedStringSetAdd(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := &lockedStringSet{set: NewStringSet()}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Add(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkConcurrentStringSetHas(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := NewConcurrentStringSetWith(keys[:benchmarkKeys/2]...)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Has(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+func BenchmarkLockedStringSetHas(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := &lockedStringSet{set: NewStringSetWith(keys[:benchmarkKeys/2]...)}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Has(keys[i%benchmarkKeys])
+		}
+	})
+}
+
+// mixed workload, one Add for every seven Has
+func BenchmarkConcurrentStringSetMixed(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := NewConcurrentStringSet()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%8 == 0 {
+				s.Add(keys[i%benchmarkKeys])
+			} else {
+				s.Has(keys[i%benchmarkKeys])
+			}
+		}
+	})
+}
+
+func BenchmarkLockedStringSetMixed(b *testing.B) {
+	keys := benchmarkKeyList()
+	s := &lockedStringSet{set: NewStringSet()}
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%8 == 0 {
+				s.Add(keys[i%benchmarkKeys])
+			} else {
+				s.Has(keys[i%benchmarkKeys])
+			}
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrInvalidEncoding = errors.New("invalid binary encoding")
+
+// The binary encoding of the string collections is a uvarint count followed
+// by uvarint length prefixed strings in sorted order.
+
+func appendUvarint(buf []byte, value uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], value)
+	return append(buf, scratch[:n]...)
+}
+
+func appendString(buf []byte, value string) []byte {
+	buf = appendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+func readUvarint(data []byte) (uint64, []byte, error) {
+	value, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, ErrInvalidEncoding
+	}
+	return value, data[n:], nil
+}
+
+func readString(data []byte) (string, []byte, error) {
+	size, data, err := readUvarint(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(data)) < size {
+		return "", nil, ErrInvalidEncoding
+	}
+	return string(data[:size]), data[size:], nil
+}
@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiMap relates every key to a set of values.
+type MultiMap[Key comparable, Value comparable] struct {
+	items map[Key]map[Value]void
+	size  int
+}
+
+func NewMultiMap[Key comparable, Value comparable]() *MultiMap[Key, Value] {
+	return &MultiMap[Key, Value]{items: map[Key]map[Value]void{}}
+}
+
+// Add relates value to key and reports whether the pair was new.
+func (m *MultiMap[Key, Value]) Add(key Key, value Value) bool {
+	values, ok := m.items[key]
+	if !ok {
+		values = map[Value]void{}
+		m.items[key] = values
+	}
+	if _, ok := values[value]; ok {
+		return false
+	}
+	values[value] = empty
+	m.size++
+	return true
+}
+
+// Remove drops the pair and reports whether it existed. Keys without values
+// are removed.
+func (m *MultiMap[Key, Value]) Remove(key Key, value Value) bool {
+	values, ok := m.items[key]
+	if !ok {
+		return false
+	}
+	if _, ok := values[value]; !ok {
+		return false
+	}
+	delete(values, value)
+	m.size--
+	if len(values) == 0 {
+		delete(m.items, key)
+	}
+	return true
+}
+
+func (m *MultiMap[Key, Value]) RemoveAll(key Key) {
+	m.size -= len(m.items[key])
+	delete(m.items, key)
+}
+
+func (m *MultiMap[Key, Value]) Get(key Key) []Value {
+	values := make([]Value, 0, len(m.items[key]))
+	for v := range m.items[key] {
+		values = append(values, v)
+	}
+	return values
+}
+
+func (m *MultiMap[Key, Value]) Has(key Key) bool {
+	_, ok := m.items[key]
+	return ok
+}
+
+func (m *MultiMap[Key, Value]) Contains(key Key, value Value) bool {
+	_, ok := m.items[key][value]
+	return ok
+}
+
+// Count returns the number of values related to key.
+func (m *MultiMap[Key, Value]) Count(key Key) int {
+	return len(m.items[key])
+}
+
+func (m *MultiMap[Key, Value]) Keys() []Key {
+	keys := make([]Key, 0, len(m.items))
+	for k := range m.items {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// KeysWithMultiple returns the keys related to more than one value.
+func (m *MultiMap[Key, Value]) KeysWithMultiple() []Key {
+	keys := []Key{}
+	for k, values := range m.items {
+		if len(values) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// ForEach calls fn for every key/value pair until fn returns false.
+func (m *MultiMap[Key, Value]) ForEach(fn func(key Key, value Value) bool) {
+	for k, values := range m.items {
+		for v := range values {
+			if !fn(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of keys.
+func (m *MultiMap[Key, Value]) Len() int {
+	return len(m.items)
+}
+
+// Size returns the number of key/value pairs.
+func (m *MultiMap[Key, Value]) Size() int {
+	return m.size
+}
+
+func (m *MultiMap[Key, Value]) String() string {
+	values := []string{}
+	for k := range m.items {
+		values = append(values, fmt.Sprintf("'%v':%v", k, m.Get(k)))
+	}
+	return fmt.Sprintf("MultiMap[%s]", strings.Join(values, ", "))
+}
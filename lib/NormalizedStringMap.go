@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type normalizedEntry struct {
+	key, value string
+}
+
+// NormalizedStringMap compares its keys by a normalized key but keeps the
+// spelling of the key which was set first for output.
+type NormalizedStringMap struct {
+	normalize Normalizer
+	items     map[string]*normalizedEntry
+}
+
+func NewNormalizedStringMap(normalize Normalizer) *NormalizedStringMap {
+	return &NormalizedStringMap{
+		normalize: normalize,
+		items:     map[string]*normalizedEntry{},
+	}
+}
+
+func (m *NormalizedStringMap) Remove(key string) {
+	delete(m.items, m.normalize(key))
+}
+
+func (m *NormalizedStringMap) Get(key string) (string, bool) {
+	if elem, ok := m.items[m.normalize(key)]; ok {
+		return elem.value, true
+	}
+	return "", false
+}
+
+func (m *NormalizedStringMap) Set(key, value string) {
+	normalized := m.normalize(key)
+	if elem, ok := m.items[normalized]; ok {
+		elem.value = value
+		return
+	}
+	m.items[normalized] = &normalizedEntry{key: key, value: value}
+}
+
+// Key returns the stored spelling of key.
+func (m *NormalizedStringMap) Key(key string) (string, bool) {
+	if elem, ok := m.items[m.normalize(key)]; ok {
+		return elem.key, true
+	}
+	return "", false
+}
+
+func (m *NormalizedStringMap) Keys() []string {
+	keys := []string{}
+	for _, e := range m.items {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (m *NormalizedStringMap) Values() []string {
+	values := []string{}
+	for _, e := range m.items {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+func (m *NormalizedStringMap) SortedKeys() []string {
+	keys := m.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// ForEach calls fn with the stored spelling of every key until fn returns
+// false.
+func (m *NormalizedStringMap) ForEach(fn func(key, value string) bool) {
+	for _, e := range m.items {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// StringMap copies the entries with their stored spelling into a StringMap.
+func (m *NormalizedStringMap) StringMap() *StringMap {
+	values := NewStringMap()
+	for _, e := range m.items {
+		values.Set(e.key, e.value)
+	}
+	return values
+}
+
+func (m *NormalizedStringMap) String() string {
+	values := []string{}
+	for _, k := range m.SortedKeys() {
+		v, _ := m.Get(k)
+		values = append(values, "'"+k+"':'"+v+"'")
+	}
+	return fmt.Sprintf("NormalizedStringMap[%s]", strings.Join(values, ", "))
+}
+
+func (m *NormalizedStringMap) Len() int {
+	return len(m.items)
+}
+
+func (m *NormalizedStringMap) Has(value string) bool {
+	_, ok := m.items[m.normalize(value)]
+	return ok
+}
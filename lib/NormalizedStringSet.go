@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NormalizedStringSet compares its values by a normalized key but keeps the
+// spelling of the first added value for output.
+type NormalizedStringSet struct {
+	normalize Normalizer
+	items     map[string]string
+}
+
+func NewNormalizedStringSet(normalize Normalizer) *NormalizedStringSet {
+	return &NormalizedStringSet{
+		normalize: normalize,
+		items:     map[string]string{},
+	}
+}
+
+func NewNormalizedStringSetWith(normalize Normalizer, keys ...string) *NormalizedStringSet {
+	s := NewNormalizedStringSet(normalize)
+
+	for _, key := range keys {
+		s.Add(key)
+	}
+
+	return s
+}
+
+func (m *NormalizedStringSet) Remove(key string) {
+	delete(m.items, m.normalize(key))
+}
+
+func (m *NormalizedStringSet) Add(key string) {
+	normalized := m.normalize(key)
+	if _, ok := m.items[normalized]; !ok {
+		m.items[normalized] = key
+	}
+}
+
+// Lookup returns the stored spelling of key.
+func (m *NormalizedStringSet) Lookup(key string) (string, bool) {
+	elem, ok := m.items[m.normalize(key)]
+	return elem, ok
+}
+
+func (m *NormalizedStringSet) Values() []string {
+	values := []string{}
+	for _, v := range m.items {
+		values = append(values, v)
+	}
+	return values
+}
+
+func (m *NormalizedStringSet) SortedValues() []string {
+	values := m.Values()
+	sort.Strings(values)
+	return values
+}
+
+// ForEach calls fn for every stored spelling until fn returns false.
+func (m *NormalizedStringSet) ForEach(fn func(value string) bool) {
+	for _, v := range m.items {
+		if !fn(v) {
+			return
+		}
+	}
+}
+
+func (m *NormalizedStringSet) String() string {
+	return fmt.Sprintf("NormalizedStringSet[%s]", strings.Join(m.SortedValues(), ", "))
+}
+
+func (m *NormalizedStringSet) Len() int {
+	return len(m.items)
+}
+
+func (m *NormalizedStringSet) Has(value string) bool {
+	_, ok := m.items[m.normalize(value)]
+	return ok
+}
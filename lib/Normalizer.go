@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"path/filepath"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer maps all spellings of a key which should be treated as equal to
+// the same comparison key.
+type Normalizer func(key string) string
+
+// FoldCase compares keys case-insensitively using Unicode case folding.
+func FoldCase(key string) string {
+	return cases.Fold().String(key)
+}
+
+// NFC compares keys in Unicode normalization form C, so a precomposed "\u00e9"
+// equals "e" followed by the combining acute accent "\u0301".
+func NFC(key string) string {
+	return norm.NFC.String(key)
+}
+
+// CleanPath compares file paths by their shortest equivalent spelling.
+func CleanPath(key string) string {
+	return filepath.Clean(key)
+}
+
+// ChainNormalizers applies the normalizers from left to right.
+func ChainNormalizers(normalizers ...Normalizer) Normalizer {
+	return func(key string) string {
+		for _, normalize := range normalizers {
+			key = normalize(key)
+		}
+		return key
+	}
+}
@@ -0,0 +1,325 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type orderedEntry struct {
+	key, value string
+	prev, next *orderedEntry
+}
+
+// OrderedStringMap is a StringMap which remembers the insertion order of its
+// keys. Get, Set, Remove and the Move operations are O(1). The zero value is
+// an empty map ready to use.
+type OrderedStringMap struct {
+	items      map[string]*orderedEntry
+	head, tail *orderedEntry
+}
+
+func NewOrderedStringMap() *OrderedStringMap {
+	return &OrderedStringMap{items: map[string]*orderedEntry{}}
+}
+
+func (m *OrderedStringMap) unlink(e *orderedEntry) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+func (m *OrderedStringMap) pushFront(e *orderedEntry) {
+	e.next = m.head
+	if m.head != nil {
+		m.head.prev = e
+	} else {
+		m.tail = e
+	}
+	m.head = e
+}
+
+func (m *OrderedStringMap) pushBack(e *orderedEntry) {
+	e.prev = m.tail
+	if m.tail != nil {
+		m.tail.next = e
+	} else {
+		m.head = e
+	}
+	m.tail = e
+}
+
+func (m *OrderedStringMap) Remove(key string) {
+	if e, ok := m.items[key]; ok {
+		m.unlink(e)
+		delete(m.items, key)
+	}
+}
+
+func (m *OrderedStringMap) Get(key string) (string, bool) {
+	if e, ok := m.items[key]; ok {
+		return e.value, true
+	}
+	return "", false
+}
+
+// Set updates the value of an existing key in place, new keys are appended.
+func (m *OrderedStringMap) Set(key, value string) {
+	if e, ok := m.items[key]; ok {
+		e.value = value
+		return
+	}
+	if m.items == nil {
+		m.items = map[string]*orderedEntry{}
+	}
+	e := &orderedEntry{key: key, value: value}
+	m.pushBack(e)
+	m.items[key] = e
+}
+
+func (m *OrderedStringMap) MoveToFront(key string) bool {
+	e, ok := m.items[key]
+	if ok {
+		m.unlink(e)
+		m.pushFront(e)
+	}
+	return ok
+}
+
+func (m *OrderedStringMap) MoveToBack(key string) bool {
+	e, ok := m.items[key]
+	if ok {
+		m.unlink(e)
+		m.pushBack(e)
+	}
+	return ok
+}
+
+// Front returns the first key and its value.
+func (m *OrderedStringMap) Front() (string, string, bool) {
+	if m.head == nil {
+		return "", "", false
+	}
+	return m.head.key, m.head.value, true
+}
+
+// Back returns the last key and its value.
+func (m *OrderedStringMap) Back() (string, string, bool) {
+	if m.tail == nil {
+		return "", "", false
+	}
+	return m.tail.key, m.tail.value, true
+}
+
+// ForEach calls fn for every entry in order until fn returns false.
+func (m *OrderedStringMap) ForEach(fn func(key, value string) bool) {
+	for e := m.head; e != nil; {
+		// fetch next first, fn may remove the current entry
+		next := e.next
+		if !fn(e.key, e.value) {
+			return
+		}
+		e = next
+	}
+}
+
+// Iter returns an iterator over the entries in insertion order in the shape of
+// iter.Seq2[string, string].
+func (m *OrderedStringMap) Iter() func(yield func(key, value string) bool) {
+	return m.ForEach
+}
+
+func (m *OrderedStringMap) Filter(predicate func(key, value string) bool) *OrderedStringMap {
+	values := NewOrderedStringMap()
+	for e := m.head; e != nil; e = e.next {
+		if predicate(e.key, e.value) {
+			values.Set(e.key, e.value)
+		}
+	}
+	return values
+}
+
+// Map builds a new map from the entries returned by fn in insertion order.
+// Entries mapped to the same key keep the position of the first one and the
+// value of the last one.
+func (m *OrderedStringMap) Map(fn func(key, value string) (string, string)) *OrderedStringMap {
+	values := NewOrderedStringMap()
+	for e := m.head; e != nil; e = e.next {
+		values.Set(fn(e.key, e.value))
+	}
+	return values
+}
+
+// Partition splits the map into the entries matching predicate and the rest,
+// both keep the insertion order.
+func (m *OrderedStringMap) Partition(predicate func(key, value string) bool) (*OrderedStringMap, *OrderedStringMap) {
+	matched, rest := NewOrderedStringMap(), NewOrderedStringMap()
+	for e := m.head; e != nil; e = e.next {
+		if predicate(e.key, e.value) {
+			matched.Set(e.key, e.value)
+		} else {
+			rest.Set(e.key, e.value)
+		}
+	}
+	return matched, rest
+}
+
+func (m *OrderedStringMap) Any(predicate func(key, value string) bool) bool {
+	for e := m.head; e != nil; e = e.next {
+		if predicate(e.key, e.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *OrderedStringMap) All(predicate func(key, value string) bool) bool {
+	for e := m.head; e != nil; e = e.next {
+		if !predicate(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupBy collects the entries into maps by the group key returned by fn.
+func (m *OrderedStringMap) GroupBy(fn func(key, value string) string) map[string]*OrderedStringMap {
+	groups := map[string]*OrderedStringMap{}
+	for e := m.head; e != nil; e = e.next {
+		group := fn(e.key, e.value)
+		if _, ok := groups[group]; !ok {
+			groups[group] = NewOrderedStringMap()
+		}
+		groups[group].Set(e.key, e.value)
+	}
+	return groups
+}
+
+func (m *OrderedStringMap) Keys() []string {
+	keys := make([]string, 0, len(m.items))
+	for e := m.head; e != nil; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (m *OrderedStringMap) Values() []string {
+	values := make([]string, 0, len(m.items))
+	for e := m.head; e != nil; e = e.next {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+func (m *OrderedStringMap) SortedKeys() []string {
+	keys := m.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// StringMap copies the entries into an unordered StringMap.
+func (m *OrderedStringMap) StringMap() *StringMap {
+	values := NewStringMap()
+	for e := m.head; e != nil; e = e.next {
+		values.Set(e.key, e.value)
+	}
+	return values
+}
+
+func (m *OrderedStringMap) String() string {
+	values := []string{}
+	for e := m.head; e != nil; e = e.next {
+		values = append(values, "'"+e.key+"':'"+e.value+"'")
+	}
+	return fmt.Sprintf("OrderedStringMap[%s]", strings.Join(values, ", "))
+}
+
+func (m *OrderedStringMap) Len() int {
+	return len(m.items)
+}
+
+func (m *OrderedStringMap) Has(value string) bool {
+	_, ok := m.items[value]
+	return ok
+}
+
+// MergeOrderedStringMaps combines layered configurations into a new map.
+// Later layers override the values of earlier ones, but every key keeps the
+// position of its first appearance.
+func MergeOrderedStringMaps(layers ...*OrderedStringMap) *OrderedStringMap {
+	merged := NewOrderedStringMap()
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		for e := layer.head; e != nil; e = e.next {
+			merged.Set(e.key, e.value)
+		}
+	}
+	return merged
+}
+
+func (m OrderedStringMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for e := m.head; e != nil; e = e.next {
+		if e != m.head {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON keeps the order of the keys in the JSON object.
+func (m *OrderedStringMap) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token == nil {
+		// null decodes into an empty map like it does for StringMap
+		*m = *NewOrderedStringMap()
+		return nil
+	} else if token != json.Delim('{') {
+		return fmt.Errorf("json: cannot decode %v into OrderedStringMap", token)
+	}
+
+	values := NewOrderedStringMap()
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value string
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		values.Set(token.(string), value)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	*m = *values
+	return nil
+}
@@ -0,0 +1,216 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type ErrorMode int
+
+const (
+	// FirstError stops at the first failing item in input order and returns
+	// its error, items not started yet are skipped
+	FirstError ErrorMode = iota
+	// AllErrors processes every item and returns all errors as MultiError
+	AllErrors
+)
+
+// Parallelism configures the parallel helpers. The zero value runs
+// GOMAXPROCS workers and stops at the first error.
+type Parallelism struct {
+	Concurrency int
+	Errors      ErrorMode
+}
+
+func (p Parallelism) workers() int {
+	if p.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return p.Concurrency
+}
+
+// ItemError is the error of the item at Index of the input.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the errors of AllErrors in input order.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target.
+func (e MultiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type parallelResult[In, Out any] struct {
+	index   int
+	item    In
+	out     Out
+	err     error
+	skipped bool
+}
+
+type parallelJob[In any] struct {
+	ticket uint64
+	index  int
+	item   In
+}
+
+// parallel runs fn over the items returned by next with the configured number
+// of workers. The results pass a ring TicketStore, so emit sees them in input
+// order on the calling goroutine while at most two results per worker wait.
+func parallel[In, Out any](ctx context.Context, p Parallelism, next func(ctx context.Context) (In, bool), fn func(ctx context.Context, item In) (Out, error), emit func(index int, item In, out Out)) error {
+	workers := p.workers()
+	work, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	store := NewRingTicketStore[parallelResult[In, Out]](2 * workers)
+	jobs := make(chan parallelJob[In])
+
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			item, ok := next(work)
+			if !ok {
+				return
+			}
+			t, err := store.ReserveContext(work)
+			if err != nil {
+				return
+			}
+			jobs <- parallelJob[In]{ticket: t, index: index, item: item}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result := parallelResult[In, Out]{index: job.index, item: job.item}
+				if work.Err() != nil {
+					result.skipped = true
+				} else {
+					result.out, result.err = fn(work, job.item)
+				}
+				// every reserved ticket gets committed, the consumer waits for it
+				_ = store.Commit(job.ticket, result)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		store.Close()
+	}()
+
+	var errs MultiError
+	for {
+		result, err := store.Receive(context.Background())
+		if err != nil {
+			break
+		}
+		switch {
+		case result.skipped:
+		case result.err != nil && p.Errors == FirstError && len(errs) > 0:
+			// failures after the first one are usually caused by the cancellation
+		case result.err != nil:
+			errs = append(errs, &ItemError{Index: result.index, Err: result.err})
+			if p.Errors == FirstError {
+				cancel()
+			}
+		case p.Errors == FirstError && len(errs) > 0:
+		default:
+			emit(result.index, result.item, result.out)
+		}
+	}
+
+	if len(errs) == 0 && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	switch {
+	case len(errs) == 0:
+		return nil
+	case p.Errors == FirstError:
+		return errs[0]
+	default:
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+		}
+		return errs
+	}
+}
+
+// ParallelMap applies fn to all items concurrently. The results keep the
+// order of the items, failed or skipped items leave the zero value.
+func ParallelMap[In, Out any](ctx context.Context, p Parallelism, items []In, fn func(ctx context.Context, item In) (Out, error)) ([]Out, error) {
+	results := make([]Out, len(items))
+	i := 0
+	err := parallel(ctx, p, func(context.Context) (In, bool) {
+		if i == len(items) {
+			var zero In
+			return zero, false
+		}
+		i++
+		return items[i-1], true
+	}, fn, func(index int, _ In, out Out) {
+		results[index] = out
+	})
+	return results, err
+}
+
+// ParallelForEach calls fn for all items concurrently.
+func ParallelForEach[In any](ctx context.Context, p Parallelism, items []In, fn func(ctx context.Context, item In) error) error {
+	_, err := ParallelMap(ctx, p, items, func(ctx context.Context, item In) (void, error) {
+		return empty, fn(ctx, item)
+	})
+	return err
+}
+
+// ParallelMapChan applies fn concurrently to the items read from the channel
+// until it is closed and calls emit with every successful result in the order
+// the items arrived. emit runs on the calling goroutine, so it needs no
+// synchronization, and slow emits hold back the workers.
+func ParallelMapChan[In, Out any](ctx context.Context, p Parallelism, items <-chan In, fn func(ctx context.Context, item In) (Out, error), emit func(item In, out Out)) error {
+	return parallel(ctx, p, func(ctx context.Context) (In, bool) {
+		select {
+		case item, ok := <-items:
+			return item, ok
+		case <-ctx.Done():
+			var zero In
+			return zero, false
+		}
+	}, fn, func(_ int, item In, out Out) {
+		emit(item, out)
+	})
+}
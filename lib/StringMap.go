@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -48,10 +50,10 @@ func (m *StringMap) SortedKeys() []string {
 	return keys
 }
 
-func (m *StringMap) String() string {
+func (m StringMap) String() string {
 	values := []string{}
-	for k, v := range *m {
-		values = append(values, "'"+k+"':'"+v+"'")
+	for _, k := range m.SortedKeys() {
+		values = append(values, "'"+k+"':'"+m[k]+"'")
 	}
 	return fmt.Sprintf("StringMap[%s]", strings.Join(values, ", "))
 }
@@ -64,3 +66,158 @@ func (m *StringMap) Has(value string) bool {
 	_, ok := (*m)[value]
 	return ok
 }
+
+func (m StringMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, k := range m.SortedKeys() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m *StringMap) UnmarshalJSON(data []byte) error {
+	values := map[string]string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*m = values
+	return nil
+}
+
+func (m StringMap) MarshalBinary() ([]byte, error) {
+	keys := m.SortedKeys()
+	buf := appendUvarint(nil, uint64(len(keys)))
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, m[k])
+	}
+	return buf, nil
+}
+
+func (m *StringMap) UnmarshalBinary(data []byte) error {
+	count, data, err := readUvarint(data)
+	if err != nil {
+		return err
+	}
+	values := StringMap{}
+	for ; count > 0; count-- {
+		var key, value string
+		if key, data, err = readString(data); err != nil {
+			return err
+		}
+		if value, data, err = readString(data); err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	if len(data) > 0 {
+		return ErrInvalidEncoding
+	}
+	*m = values
+	return nil
+}
+
+// GobEncode uses the binary encoding. It differs from the plain map encoding
+// gob used before, such maps do not decode into a StringMap.
+func (m StringMap) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+func (m *StringMap) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// ForEach calls fn for every entry until fn returns false. It iterates the
+// map in place without allocating.
+func (m *StringMap) ForEach(fn func(key, value string) bool) {
+	for k, v := range *m {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Iter returns an iterator over the entries in the shape of
+// iter.Seq2[string, string].
+func (m *StringMap) Iter() func(yield func(key, value string) bool) {
+	return m.ForEach
+}
+
+func (m *StringMap) Filter(predicate func(key, value string) bool) *StringMap {
+	values := NewStringMap()
+	for k, v := range *m {
+		if predicate(k, v) {
+			values.Set(k, v)
+		}
+	}
+	return values
+}
+
+// Map builds a new map from the entries returned by fn. Entries mapped to the
+// same key overwrite each other in unspecified order.
+func (m *StringMap) Map(fn func(key, value string) (string, string)) *StringMap {
+	values := NewStringMap()
+	for k, v := range *m {
+		values.Set(fn(k, v))
+	}
+	return values
+}
+
+// Partition splits the map into the entries matching predicate and the rest.
+func (m *StringMap) Partition(predicate func(key, value string) bool) (*StringMap, *StringMap) {
+	matched, rest := NewStringMap(), NewStringMap()
+	for k, v := range *m {
+		if predicate(k, v) {
+			matched.Set(k, v)
+		} else {
+			rest.Set(k, v)
+		}
+	}
+	return matched, rest
+}
+
+func (m *StringMap) Any(predicate func(key, value string) bool) bool {
+	for k, v := range *m {
+		if predicate(k, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *StringMap) All(predicate func(key, value string) bool) bool {
+	for k, v := range *m {
+		if !predicate(k, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupBy collects the entries into maps by the group key returned by fn.
+func (m *StringMap) GroupBy(fn func(key, value string) string) map[string]*StringMap {
+	groups := map[string]*StringMap{}
+	for k, v := range *m {
+		group := fn(k, v)
+		if _, ok := groups[group]; !ok {
+			groups[group] = NewStringMap()
+		}
+		groups[group].Set(k, v)
+	}
+	return groups
+}
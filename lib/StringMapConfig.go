@@ -0,0 +1,324 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+func (m *StringMap) UnmarshalYAML(node *yaml.Node) error {
+	values := StringMap{}
+	if err := decodeYAMLMapping(node, values.Set); err != nil {
+		return err
+	}
+	*m = values
+	return nil
+}
+
+func (m StringMap) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range m.SortedKeys() {
+		node.Content = append(node.Content, yamlString(k), yamlString(m[k]))
+	}
+	return node, nil
+}
+
+// UnmarshalTOML accepts a table of scalars. Non-string scalars like numbers,
+// booleans and datetimes are stored in their TOML notation.
+func (m *StringMap) UnmarshalTOML(data interface{}) error {
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("toml: cannot decode %T into StringMap", data)
+	}
+	values := StringMap{}
+	for k, v := range table {
+		value, err := tomlScalar(k, v)
+		if err != nil {
+			return err
+		}
+		values[k] = value
+	}
+	*m = values
+	return nil
+}
+
+func (m *OrderedStringMap) UnmarshalYAML(node *yaml.Node) error {
+	values := NewOrderedStringMap()
+	if err := decodeYAMLMapping(node, values.Set); err != nil {
+		return err
+	}
+	*m = *values
+	return nil
+}
+
+func (m OrderedStringMap) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for e := m.head; e != nil; e = e.next {
+		node.Content = append(node.Content, yamlString(e.key), yamlString(e.value))
+	}
+	return node, nil
+}
+
+// UnmarshalTOML is used for nested tables. The toml decoder hands over an
+// unordered table, so the keys are sorted. Use DecodeTOML to keep the order
+// of a document.
+func (m *OrderedStringMap) UnmarshalTOML(data interface{}) error {
+	values := StringMap{}
+	if err := values.UnmarshalTOML(data); err != nil {
+		return err
+	}
+	ordered := NewOrderedStringMap()
+	for _, k := range values.SortedKeys() {
+		ordered.Set(k, values[k])
+	}
+	*m = *ordered
+	return nil
+}
+
+// MarshalTOML writes an inline table in insertion order.
+func (m OrderedStringMap) MarshalTOML() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for e := m.head; e != nil; e = e.next {
+		if e != m.head {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(tomlKey(e.key))
+		buf.WriteString(" = ")
+		buf.WriteString(tomlString(e.value))
+	}
+	if m.head != nil {
+		buf.WriteByte(' ')
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// DecodeTOML reads a toml document of top level key/value pairs in document
+// order.
+func (m *OrderedStringMap) DecodeTOML(data []byte) error {
+	table := map[string]interface{}{}
+	meta, err := toml.Decode(string(data), &table)
+	if err != nil {
+		return err
+	}
+	values := NewOrderedStringMap()
+	for _, key := range meta.Keys() {
+		if len(key) != 1 {
+			continue
+		}
+		value, err := tomlScalar(key[0], table[key[0]])
+		if err != nil {
+			return err
+		}
+		values.Set(key[0], value)
+	}
+	*m = *values
+	return nil
+}
+
+// EncodeTOML writes a toml document of top level key/value pairs in
+// insertion order.
+func (m *OrderedStringMap) EncodeTOML() []byte {
+	buf := &bytes.Buffer{}
+	for e := m.head; e != nil; e = e.next {
+		buf.WriteString(tomlKey(e.key))
+		buf.WriteString(" = ")
+		buf.WriteString(tomlString(e.value))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// ReadStringMapFile decodes a yaml or toml file depending on its extension.
+func ReadStringMapFile(path string) (*StringMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewStringMap()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, m)
+	case ".toml":
+		err = toml.Unmarshal(data, m)
+	default:
+		err = fmt.Errorf("unsupported config format '%s'", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read '%s': %w", path, err)
+	}
+	return m, nil
+}
+
+// ReadOrderedStringMapFile decodes a yaml or toml file depending on its
+// extension and keeps the order of the document.
+func ReadOrderedStringMapFile(path string) (*OrderedStringMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewOrderedStringMap()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, m)
+	case ".toml":
+		err = m.DecodeTOML(data)
+	default:
+		err = fmt.Errorf("unsupported config format '%s'", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read '%s': %w", path, err)
+	}
+	return m, nil
+}
+
+// MergeStringMaps combines layered configurations into a new map. Later
+// layers override the values of earlier ones, nil layers are skipped.
+func MergeStringMaps(layers ...*StringMap) *StringMap {
+	merged := NewStringMap()
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		for k, v := range *layer {
+			merged.Set(k, v)
+		}
+	}
+	return merged
+}
+
+// decodeYAMLMapping calls set for every entry of a mapping node in document
+// order. Aliases are resolved and merge keys ('<<') are applied before the
+// explicit entries, so the explicit entries take precedence.
+func decodeYAMLMapping(node *yaml.Node, set func(key, value string)) error {
+	node = resolveYAMLAlias(node)
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = resolveYAMLAlias(node.Content[0])
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("yaml: line %d: expected a mapping of strings", node.Line)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveYAMLAlias(node.Content[i+1])
+		if key.Tag != "!!merge" {
+			continue
+		}
+		merges := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merges = value.Content
+		}
+		for _, merge := range merges {
+			if err := decodeYAMLMapping(merge, set); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveYAMLAlias(node.Content[i+1])
+		if key.Tag == "!!merge" {
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return fmt.Errorf("yaml: line %d: expected a scalar key", key.Line)
+		}
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			set(key.Value, "")
+		case value.Kind == yaml.ScalarNode:
+			set(key.Value, value.Value)
+		default:
+			return fmt.Errorf("yaml: line %d: expected a scalar value for '%s'", value.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+func resolveYAMLAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func yamlString(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func tomlScalar(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return tomlTime(v), nil
+	case map[string]interface{}, []map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("toml: expected a scalar value for '%s'", key)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// tomlTime writes a datetime in its TOML notation. The decoder marks local
+// datetimes, dates and times by the name of their zone.
+func tomlTime(t time.Time) string {
+	switch t.Location().String() {
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case "date-local":
+		return t.Format("2006-01-02")
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+func tomlString(value string) string {
+	buf := &strings.Builder{}
+	buf.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(buf, `\u%04X`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const tomlDocument = `
+name = "indexer"
+workers = 4
+ratio = 0.5
+enabled = true
+offset = 2020-01-01T10:00:00+02:00
+utc = 2020-01-01T00:00:00Z
+fraction = 2020-01-01T00:00:00.25Z
+local = 2020-01-01T10:00:00
+day = 2020-01-01
+alarm = 07:30:00
+`
+
+var tomlValues = StringMap{
+	"name":     "indexer",
+	"workers":  "4",
+	"ratio":    "0.5",
+	"enabled":  "true",
+	"offset":   "2020-01-01T10:00:00+02:00",
+	"utc":      "2020-01-01T00:00:00Z",
+	"fraction": "2020-01-01T00:00:00.25Z",
+	"local":    "2020-01-01T10:00:00",
+	"day":      "2020-01-01",
+	"alarm":    "07:30:00",
+}
+
+func TestStringMapTOML(t *testing.T) {
+	m := StringMap{}
+	if err := toml.Unmarshal([]byte(tomlDocument), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, tomlValues) {
+		t.Errorf("decoded %v, want %v", m, tomlValues)
+	}
+
+	if err := toml.Unmarshal([]byte("table = { a = 1 }"), &m); err == nil {
+		t.Error("decoded a nested table")
+	}
+}
+
+func TestOrderedStringMapTOML(t *testing.T) {
+	m := NewOrderedStringMap()
+	if err := m.DecodeTOML([]byte(tomlDocument)); err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for e := m.head; e != nil; e = e.next {
+		keys = append(keys, e.key)
+		if e.value != tomlValues[e.key] {
+			t.Errorf("%s = %q, want %q", e.key, e.value, tomlValues[e.key])
+		}
+	}
+	want := []string{"name", "workers", "ratio", "enabled", "offset", "utc", "fraction", "local", "day", "alarm"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys %v, want %v", keys, want)
+	}
+}
+
+func TestStringMapYAMLValue(t *testing.T) {
+	data, err := yaml.Marshal(map[string]StringMap{"x": {"b": "2", "a": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x:\n    a: \"1\"\n    b: \"2\"\n"; string(data) != want {
+		t.Errorf("yaml %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func fmtString(value any) string {
+	return fmt.Sprint(value)
+}
+
+func TestStringMapMarshalValues(t *testing.T) {
+	m := StringMap{"b": "2", "a": "1"}
+	maps := map[string]StringMap{"x": m}
+	data, err := json.Marshal(maps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"x":{"a":"1","b":"2"}}`; string(data) != want {
+		t.Errorf("json %s, want %s", data, want)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(maps); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]StringMap
+	if err := gob.NewDecoder(buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, maps) {
+		t.Errorf("gob decoded %v, want %v", decoded, maps)
+	}
+	if s := fmtString(any(m)); s != "StringMap['a':'1', 'b':'2']" {
+		t.Errorf("String %q", s)
+	}
+}
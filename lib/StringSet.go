@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -47,8 +48,8 @@ func (m *StringSet) SortedValues() []string {
 	return values
 }
 
-func (m *StringSet) String() string {
-	return fmt.Sprintf("StringSet[%s]", strings.Join(m.Values(), ", "))
+func (m StringSet) String() string {
+	return fmt.Sprintf("StringSet[%s]", strings.Join(m.SortedValues(), ", "))
 }
 
 func (m *StringSet) Len() int {
@@ -59,3 +60,132 @@ func (m *StringSet) Has(value string) bool {
 	_, ok := (*m)[value]
 	return ok
 }
+
+func (m StringSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.SortedValues())
+}
+
+func (m *StringSet) UnmarshalJSON(data []byte) error {
+	values := []string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*m = *NewStringSetWith(values...)
+	return nil
+}
+
+func (m StringSet) MarshalBinary() ([]byte, error) {
+	values := m.SortedValues()
+	buf := appendUvarint(nil, uint64(len(values)))
+	for _, value := range values {
+		buf = appendString(buf, value)
+	}
+	return buf, nil
+}
+
+func (m *StringSet) UnmarshalBinary(data []byte) error {
+	count, data, err := readUvarint(data)
+	if err != nil {
+		return err
+	}
+	s := StringSet{}
+	for ; count > 0; count-- {
+		var value string
+		if value, data, err = readString(data); err != nil {
+			return err
+		}
+		s[value] = empty
+	}
+	if len(data) > 0 {
+		return ErrInvalidEncoding
+	}
+	*m = s
+	return nil
+}
+
+// GobEncode uses the binary encoding. It differs from the plain map encoding
+// gob used before, such sets do not decode into a StringSet.
+func (m StringSet) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
+}
+
+func (m *StringSet) GobDecode(data []byte) error {
+	return m.UnmarshalBinary(data)
+}
+
+// ForEach calls fn for every value until fn returns false. It iterates the
+// set in place without allocating.
+func (m *StringSet) ForEach(fn func(value string) bool) {
+	for k := range *m {
+		if !fn(k) {
+			return
+		}
+	}
+}
+
+// Iter returns an iterator over the values in the shape of iter.Seq[string].
+func (m *StringSet) Iter() func(yield func(value string) bool) {
+	return m.ForEach
+}
+
+func (m *StringSet) Filter(predicate func(value string) bool) *StringSet {
+	s := NewStringSet()
+	for k := range *m {
+		if predicate(k) {
+			s.Add(k)
+		}
+	}
+	return s
+}
+
+func (m *StringSet) Map(fn func(value string) string) *StringSet {
+	s := NewStringSet()
+	for k := range *m {
+		s.Add(fn(k))
+	}
+	return s
+}
+
+// Partition splits the set into the values matching predicate and the rest.
+func (m *StringSet) Partition(predicate func(value string) bool) (*StringSet, *StringSet) {
+	matched, rest := NewStringSet(), NewStringSet()
+	for k := range *m {
+		if predicate(k) {
+			matched.Add(k)
+		} else {
+			rest.Add(k)
+		}
+	}
+	return matched, rest
+}
+
+func (m *StringSet) Any(predicate func(value string) bool) bool {
+	for k := range *m {
+		if predicate(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *StringSet) All(predicate func(value string) bool) bool {
+	for k := range *m {
+		if !predicate(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupBy collects the values into sets by the group key returned by fn.
+func (m *StringSet) GroupBy(fn func(value string) string) map[string]*StringSet {
+	groups := map[string]*StringSet{}
+	for k := range *m {
+		group := fn(k)
+		if _, ok := groups[group]; !ok {
+			groups[group] = NewStringSet()
+		}
+		groups[group].Add(k)
+	}
+	return groups
+}
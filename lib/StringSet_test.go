@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStringSetMarshalValues(t *testing.T) {
+	sets := map[string]StringSet{"x": *NewStringSetWith("b", "a"), "y": {}}
+	data, err := json.Marshal(sets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"x":["a","b"],"y":[]}`; string(data) != want {
+		t.Errorf("json %s, want %s", data, want)
+	}
+
+	var decoded map[string]StringSet
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, sets) {
+		t.Errorf("decoded %v, want %v", decoded, sets)
+	}
+
+	// an interface holds a copy which is not addressable
+	data, err = json.Marshal([]any{*NewStringSetWith("b", "a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[["a","b"]]`; string(data) != want {
+		t.Errorf("json %s, want %s", data, want)
+	}
+}
+
+func TestStringSetBinaryValues(t *testing.T) {
+	set := *NewStringSetWith("b", "a", "c")
+	first, err := set.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		again, _ := NewStringSetWith("c", "a", "b").MarshalBinary()
+		if !bytes.Equal(first, again) {
+			t.Fatalf("binary encoding differs: %x, %x", first, again)
+		}
+	}
+
+	sets := map[string]StringSet{"x": set}
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(sets); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]StringSet
+	if err := gob.NewDecoder(buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, sets) {
+		t.Errorf("gob decoded %v, want %v", decoded, sets)
+	}
+	if s := fmtString(sets["x"]); s != "StringSet[a, b, c]" {
+		t.Errorf("String %q", s)
+	}
+}
@@ -0,0 +1,267 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// The state of a slot holds the ticket it belongs to shifted by two bits and
+// the phase of that ticket in the low bits, so a single compare and swap
+// checks both.
+const (
+	// slotOpen waits for the commit of the ticket
+	slotOpen = iota
+	// slotWriting marks a value being committed
+	slotWriting
+	// slotCommitted marks a value ready to be read
+	slotCommitted
+	phaseBits = 2
+)
+
+func slotState(t uint64, phase uint64) uint64 {
+	return t<<phaseBits | phase
+}
+
+type ringSlot[Value any] struct {
+	state uint64
+	value Value
+}
+
+// ticketRing is the fixed capacity mode of the TicketStore. Producers and the
+// committing workers only use atomic operations, a single consumer frees the
+// slots in ticket order. Waiting for space or values blocks without spinning.
+type ticketRing[Value any] struct {
+	ticket uint64
+	read   uint64
+	closed uint32
+	slots  []ringSlot[Value]
+
+	// consumer serializes the consuming side
+	consumer sync.Mutex
+
+	waiters int32
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+func newTicketRing[Value any](capacity int) *ticketRing[Value] {
+	if capacity <= 0 {
+		panic(fmt.Sprintf("ticket ring capacity %d must be positive", capacity))
+	}
+	r := &ticketRing[Value]{slots: make([]ringSlot[Value], capacity)}
+	for i := range r.slots {
+		r.slots[i].state = slotState(uint64(i), slotOpen)
+	}
+	return r
+}
+
+func (r *ticketRing[Value]) slot(t uint64) *ringSlot[Value] {
+	return &r.slots[t%uint64(len(r.slots))]
+}
+
+func (r *ticketRing[Value]) isClosed() bool {
+	return atomic.LoadUint32(&r.closed) != 0
+}
+
+// await blocks until ready reports true or ctx ends. A waiter registers
+// before it checks ready a last time, so signal never misses it.
+func (r *ticketRing[Value]) await(ctx context.Context, ready func() bool) error {
+	for !ready() {
+		atomic.AddInt32(&r.waiters, 1)
+		r.mu.Lock()
+		if r.changed == nil {
+			r.changed = make(chan struct{})
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		if ready() {
+			atomic.AddInt32(&r.waiters, -1)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			atomic.AddInt32(&r.waiters, -1)
+			return ctx.Err()
+		case <-changed:
+			atomic.AddInt32(&r.waiters, -1)
+		}
+	}
+	return nil
+}
+
+// signal wakes up all waiters, it only locks if somebody waits.
+func (r *ticketRing[Value]) signal() {
+	if atomic.LoadInt32(&r.waiters) == 0 {
+		return
+	}
+	r.mu.Lock()
+	if r.changed != nil {
+		close(r.changed)
+		r.changed = nil
+	}
+	r.mu.Unlock()
+}
+
+func (r *ticketRing[Value]) hasSpace() bool {
+	read := atomic.LoadUint64(&r.read)
+	return atomic.LoadUint64(&r.ticket)-read < uint64(len(r.slots))
+}
+
+// reserve draws the next ticket once its slot was freed by the consumer.
+func (r *ticketRing[Value]) reserve(ctx context.Context) (uint64, error) {
+	for {
+		if r.isClosed() {
+			panic("reserve on closed TicketStore")
+		}
+		// load read first, it never passes the ticket
+		read := atomic.LoadUint64(&r.read)
+		t := atomic.LoadUint64(&r.ticket)
+		if t-read >= uint64(len(r.slots)) {
+			if err := r.await(ctx, r.hasSpace); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if atomic.CompareAndSwapUint64(&r.ticket, t, t+1) {
+			return t, nil
+		}
+	}
+}
+
+func (r *ticketRing[Value]) commit(t uint64, s Value) error {
+	if t >= atomic.LoadUint64(&r.ticket) {
+		return fmt.Errorf("commit %d: %w", t, ErrTicketUnknown)
+	}
+	// a reserved ticket finds its slot open, committed or already handed
+	// over to a later ticket
+	slot := r.slot(t)
+	if !atomic.CompareAndSwapUint64(&slot.state, slotState(t, slotOpen), slotState(t, slotWriting)) {
+		return fmt.Errorf("commit %d: %w", t, ErrTicketCommitted)
+	}
+	slot.value = s
+	atomic.StoreUint64(&slot.state, slotState(t, slotCommitted))
+	r.signal()
+	return nil
+}
+
+func (r *ticketRing[Value]) close() {
+	atomic.StoreUint32(&r.closed, 1)
+	r.signal()
+}
+
+// committed reports whether the value of ticket t is ready to be read.
+func (r *ticketRing[Value]) committed(t uint64) bool {
+	return atomic.LoadUint64(&r.slot(t).state) == slotState(t, slotCommitted)
+}
+
+// done counts all values ever done, the consumed ones and the contiguous
+// committed ones still held by the ring.
+func (r *ticketRing[Value]) done() uint64 {
+	t := atomic.LoadUint64(&r.read)
+	for end := t + uint64(len(r.slots)); t < end && r.committed(t); t++ {
+	}
+	return t
+}
+
+// next frees the slot of the next ticket if it is committed, the consumer
+// lock must be held.
+func (r *ticketRing[Value]) next() (Value, bool) {
+	var zero Value
+	t := atomic.LoadUint64(&r.read)
+	if !r.committed(t) {
+		return zero, false
+	}
+	slot := r.slot(t)
+	s := slot.value
+	slot.value = zero
+	atomic.StoreUint64(&slot.state, slotState(t+uint64(len(r.slots)), slotOpen))
+	atomic.StoreUint64(&r.read, t+1)
+	return s, true
+}
+
+func (r *ticketRing[Value]) tryNext() (Value, bool) {
+	r.consumer.Lock()
+	s, ok := r.next()
+	r.consumer.Unlock()
+	if ok {
+		r.signal()
+	}
+	return s, ok
+}
+
+func (r *ticketRing[Value]) drained() bool {
+	return r.isClosed() && atomic.LoadUint64(&r.read) == atomic.LoadUint64(&r.ticket)
+}
+
+func (r *ticketRing[Value]) receive(ctx context.Context) (Value, error) {
+	for {
+		if s, ok := r.tryNext(); ok {
+			return s, nil
+		}
+		if r.drained() {
+			var zero Value
+			return zero, ErrTicketStoreClosed
+		}
+		err := r.await(ctx, func() bool {
+			return r.committed(atomic.LoadUint64(&r.read)) || r.drained()
+		})
+		if err != nil {
+			var zero Value
+			return zero, err
+		}
+	}
+}
+
+func (r *ticketRing[Value]) takeDone() []Value {
+	r.consumer.Lock()
+	values := []Value{}
+	for {
+		s, ok := r.next()
+		if !ok {
+			break
+		}
+		values = append(values, s)
+	}
+	r.consumer.Unlock()
+	if len(values) > 0 {
+		r.signal()
+	}
+	return values
+}
+
+// getDone copies the done values which are not consumed yet.
+func (r *ticketRing[Value]) getDone() []Value {
+	r.consumer.Lock()
+	defer r.consumer.Unlock()
+
+	values := []Value{}
+	for t, end := atomic.LoadUint64(&r.read), r.done(); t < end; t++ {
+		values = append(values, r.slot(t).value)
+	}
+	return values
+}
+
+func (r *ticketRing[Value]) waitFor(ctx context.Context, n int) error {
+	// done may lag behind while the consumer frees a slot, so the last
+	// result decides instead of a second look
+	var done uint64
+	err := r.await(ctx, func() bool {
+		done = r.done()
+		return done >= uint64(n) || r.isClosed() && atomic.LoadUint64(&r.ticket) < uint64(n)
+	})
+	if err != nil {
+		return err
+	}
+	if done < uint64(n) {
+		return fmt.Errorf("wait for %d: %w", n, ErrTicketStoreClosed)
+	}
+	return nil
+}
+
+func (r *ticketRing[Value]) pending() int {
+	done := r.done()
+	return int(atomic.LoadUint64(&r.ticket) - done)
+}
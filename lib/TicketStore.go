@@ -1,14 +1,39 @@
 package lib
 
 import (
-	"runtime"
-	"sync/atomic"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 )
 
+var (
+	ErrTicketUnknown   = errors.New("ticket not reserved")
+	ErrTicketCommitted = errors.New("ticket already committed")
+	// ErrTicketStoreClosed reports that no more values will become done.
+	ErrTicketStoreClosed = errors.New("ticket store closed")
+)
+
+// TicketStore collects values from concurrent producers in ticket order.
+// Producers Reserve a ticket, e.g. in input order, and Commit the value later.
+// A value is done once it and all values of smaller tickets are committed.
+// Consumers block on WaitFor, Receive or Chan until values are done. The
+// store grows as needed, a ring store created by NewRingTicketStore reuses a
+// fixed number of slots instead.
 type TicketStore[Value any] struct {
+	mu     sync.Mutex
 	ticket uint64
 	done   uint64
+	// read is the ticket of the next value handed to the consumer
+	read   uint64
 	slots  []Value
+	filled []bool
+	closed bool
+	// changed is closed to wake up all waiters when done grows or the store
+	// is closed, it is created on demand by the first waiter
+	changed chan struct{}
+
+	ring *ticketRing[Value]
 }
 
 func NewTicketStore[Value any]() *TicketStore[Value] {
@@ -18,15 +43,257 @@ func NewTicketStore[Value any]() *TicketStore[Value] {
 	}
 }
 
-func (ts *TicketStore[Value]) Put(s Value) {
-	t := atomic.AddUint64(&ts.ticket, 1) - 1
+// NewRingTicketStore creates a store with capacity slots which are reused once
+// the consumer took their values with Next, TakeDone, Receive or Chan. Reserve
+// blocks while all slots are in use, which applies backpressure to the
+// producers. Producers never lock, so the ring works as a bounded ordered
+// multi-producer single-consumer queue. GetDone only returns the values not
+// yet taken.
+func NewRingTicketStore[Value any](capacity int) *TicketStore[Value] {
+	return &TicketStore[Value]{ring: newTicketRing[Value](capacity)}
+}
+
+func (ts *TicketStore[Value]) wait() <-chan struct{} {
+	if ts.changed == nil {
+		ts.changed = make(chan struct{})
+	}
+	return ts.changed
+}
+
+func (ts *TicketStore[Value]) signal() {
+	if ts.changed != nil {
+		close(ts.changed)
+		ts.changed = nil
+	}
+}
+
+// Reserve draws the next ticket, its value is committed later. Reserve
+// panics if the store is closed.
+func (ts *TicketStore[Value]) Reserve() uint64 {
+	t, _ := ts.ReserveContext(context.Background())
+	return t
+}
+
+// ReserveContext is Reserve which gives up when ctx ends while a ring store
+// is full.
+func (ts *TicketStore[Value]) ReserveContext(ctx context.Context) (uint64, error) {
+	if ts.ring != nil {
+		return ts.ring.reserve(ctx)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closed {
+		panic("reserve on closed TicketStore")
+	}
+
+	var zero Value
+	t := ts.ticket
+	ts.ticket++
+	ts.slots = append(ts.slots, zero)
+	ts.filled = append(ts.filled, false)
+	return t, nil
+}
+
+// Commit stores s for a reserved ticket and advances the done prefix.
+func (ts *TicketStore[Value]) Commit(t uint64, s Value) error {
+	if ts.ring != nil {
+		return ts.ring.commit(t, s)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if t >= ts.ticket {
+		return fmt.Errorf("commit %d: %w", t, ErrTicketUnknown)
+	}
+	if ts.filled[t] {
+		return fmt.Errorf("commit %d: %w", t, ErrTicketCommitted)
+	}
 	ts.slots[t] = s
+	ts.filled[t] = true
+	if t != ts.done {
+		return nil
+	}
+	for ts.done < ts.ticket && ts.filled[ts.done] {
+		ts.done++
+	}
+	ts.signal()
+	return nil
+}
+
+// Close announces that no more tickets will be reserved. Reserved tickets may
+// still be committed.
+func (ts *TicketStore[Value]) Close() {
+	if ts.ring != nil {
+		ts.ring.close()
+		return
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.closed = true
+	ts.signal()
+}
+
+// WaitFor blocks until at least n values are done. It fails if ctx ends or
+// the store is closed with fewer than n tickets.
+func (ts *TicketStore[Value]) WaitFor(ctx context.Context, n int) error {
+	if ts.ring != nil {
+		return ts.ring.waitFor(ctx, n)
+	}
 
-	for !atomic.CompareAndSwapUint64(&ts.done, t, t+1) {
-		runtime.Gosched()
+	for {
+		ts.mu.Lock()
+		if ts.done >= uint64(n) {
+			ts.mu.Unlock()
+			return nil
+		}
+		if ts.closed && ts.ticket < uint64(n) {
+			ts.mu.Unlock()
+			return fmt.Errorf("wait for %d: %w", n, ErrTicketStoreClosed)
+		}
+		changed := ts.wait()
+		ts.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Receive blocks until the value of the next ticket is done and hands it to
+// the consumer like Next. It returns ErrTicketStoreClosed once the store is
+// closed and every value was received.
+func (ts *TicketStore[Value]) Receive(ctx context.Context) (Value, error) {
+	if ts.ring != nil {
+		return ts.ring.receive(ctx)
+	}
+
+	for {
+		ts.mu.Lock()
+		if ts.read < ts.done {
+			s := ts.slots[ts.read]
+			ts.read++
+			ts.mu.Unlock()
+			return s, nil
+		}
+		if ts.closed && ts.read == ts.ticket {
+			ts.mu.Unlock()
+			var zero Value
+			return zero, ErrTicketStoreClosed
+		}
+		changed := ts.wait()
+		ts.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			var zero Value
+			return zero, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Chan emits the done values in ticket order as Receive does. The channel is
+// closed when the store is closed and drained or when ctx ends.
+func (ts *TicketStore[Value]) Chan(ctx context.Context) <-chan Value {
+	values := make(chan Value)
+	go func() {
+		defer close(values)
+		for {
+			s, err := ts.Receive(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case values <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return values
+}
+
+// Put stores s under the next ticket and returns the ticket.
+func (ts *TicketStore[Value]) Put(s Value) uint64 {
+	t := ts.Reserve()
+	// a fresh ticket is always known and uncommitted
+	_ = ts.Commit(t, s)
+	return t
+}
+
+// Next hands the value of the next ticket to the consumer once it is done.
+// Values are returned strictly in ticket order, every value exactly once.
+func (ts *TicketStore[Value]) Next() (Value, bool) {
+	if ts.ring != nil {
+		return ts.ring.tryNext()
 	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.read == ts.done {
+		var zero Value
+		return zero, false
+	}
+	s := ts.slots[ts.read]
+	ts.read++
+	return s, true
 }
 
+// TakeDone hands all done values not yet taken to the consumer.
+func (ts *TicketStore[Value]) TakeDone() []Value {
+	if ts.ring != nil {
+		return ts.ring.takeDone()
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	values := ts.slots[ts.read:ts.done:ts.done]
+	ts.read = ts.done
+	return values
+}
+
+// GetDone returns the completed values in ticket order. The returned slice
+// must not be modified. A ring store returns a copy of the values not taken.
 func (ts *TicketStore[Value]) GetDone() []Value {
-	return ts.slots[:atomic.LoadUint64(&ts.done)+1]
+	if ts.ring != nil {
+		return ts.ring.getDone()
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.slots[:ts.done:ts.done]
+}
+
+// Len returns the number of completed values.
+func (ts *TicketStore[Value]) Len() int {
+	if ts.ring != nil {
+		return int(ts.ring.done())
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return int(ts.done)
+}
+
+// Pending returns the number of reserved tickets which are not done yet.
+func (ts *TicketStore[Value]) Pending() int {
+	if ts.ring != nil {
+		return ts.ring.pending()
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return int(ts.ticket - ts.done)
 }
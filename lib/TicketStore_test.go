@@ -0,0 +1,272 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type ticketStoreCase struct {
+	name string
+	new  func() *TicketStore[uint64]
+}
+
+var ticketStoreCases = []ticketStoreCase{
+	{"growable", NewTicketStore[uint64]},
+	{"ring", func() *TicketStore[uint64] { return NewRingTicketStore[uint64](16) }},
+}
+
+// commitConcurrently reserves n tickets, a multiple of producers, from
+// producers goroutines and commits every ticket as its value after a random
+// delay, then closes the store.
+func commitConcurrently(t *testing.T, ts *TicketStore[uint64], n, producers int) {
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for i := 0; i < n/producers; i++ {
+				ticket := ts.Reserve()
+				if random.Intn(4) == 0 {
+					runtime.Gosched()
+				}
+				if err := ts.Commit(ticket, ticket); err != nil {
+					t.Error(err)
+				}
+			}
+		}(int64(p))
+	}
+	go func() {
+		wg.Wait()
+		ts.Close()
+	}()
+}
+
+func TestTicketStoreOrderedCommit(t *testing.T) {
+	const n, producers = 20000, 32
+	for _, c := range ticketStoreCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := c.new()
+			commitConcurrently(t, ts, n, producers)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			next := uint64(0)
+			for value := range ts.Chan(ctx) {
+				if value != next {
+					t.Fatalf("received %d, want %d", value, next)
+				}
+				next++
+			}
+			if next != n {
+				t.Fatalf("received %d values, want %d", next, n)
+			}
+			if _, err := ts.Receive(ctx); !errors.Is(err, ErrTicketStoreClosed) {
+				t.Errorf("receive after drain: %v", err)
+			}
+			if ts.Len() != n || ts.Pending() != 0 {
+				t.Errorf("len %d, pending %d", ts.Len(), ts.Pending())
+			}
+		})
+	}
+}
+
+func TestTicketStoreDoneBounds(t *testing.T) {
+	const n, producers = 4800, 16
+	for _, c := range ticketStoreCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := c.new()
+			commitConcurrently(t, ts, n, producers)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			taken, last := uint64(0), 0
+			for {
+				length := ts.Len()
+				if length < last || length > n {
+					t.Fatalf("len %d after %d", length, last)
+				}
+				last = length
+
+				// done values are the committed prefix not taken yet
+				done := ts.GetDone()
+				start := uint64(0)
+				if c.name == "ring" {
+					start = taken
+				}
+				for i, value := range done {
+					if value != start+uint64(i) {
+						t.Fatalf("done value %d at %d", value, i)
+					}
+				}
+				if int(start)+len(done) > ts.Len() {
+					t.Fatalf("%d done values beyond len %d", int(start)+len(done), ts.Len())
+				}
+
+				if _, err := ts.Receive(ctx); errors.Is(err, ErrTicketStoreClosed) {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				taken++
+			}
+			if taken != n || ts.Len() != n {
+				t.Errorf("taken %d, len %d", taken, ts.Len())
+			}
+		})
+	}
+}
+
+func TestTicketStoreGaps(t *testing.T) {
+	for _, c := range ticketStoreCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := c.new()
+			for i := 0; i < 3; i++ {
+				ts.Reserve()
+			}
+			ts.Commit(0, 0)
+			ts.Commit(2, 2)
+			if ts.Len() != 1 || ts.Pending() != 2 || len(ts.GetDone()) != 1 {
+				t.Fatalf("len %d, pending %d, done %v", ts.Len(), ts.Pending(), ts.GetDone())
+			}
+			ts.Commit(1, 1)
+			if ts.Len() != 3 || ts.Pending() != 0 {
+				t.Fatalf("len %d, pending %d", ts.Len(), ts.Pending())
+			}
+			if done := ts.GetDone(); len(done) != 3 || done[0] != 0 || done[1] != 1 || done[2] != 2 {
+				t.Fatalf("done %v", done)
+			}
+		})
+	}
+}
+
+func TestTicketStoreCommitErrors(t *testing.T) {
+	for _, c := range ticketStoreCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := c.new()
+			if err := ts.Commit(0, 0); !errors.Is(err, ErrTicketUnknown) {
+				t.Errorf("commit unreserved: %v", err)
+			}
+			ticket := ts.Reserve()
+			if err := ts.Commit(ticket+1, 0); !errors.Is(err, ErrTicketUnknown) {
+				t.Errorf("commit unknown: %v", err)
+			}
+			if err := ts.Commit(ticket, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := ts.Commit(ticket, 2); !errors.Is(err, ErrTicketCommitted) {
+				t.Errorf("commit twice: %v", err)
+			}
+			if value, ok := ts.Next(); !ok || value != 1 {
+				t.Fatalf("next %d %v", value, ok)
+			}
+			// the slot of a taken ticket may be reused by the ring
+			if err := ts.Commit(ticket, 3); !errors.Is(err, ErrTicketCommitted) {
+				t.Errorf("commit taken: %v", err)
+			}
+		})
+	}
+}
+
+func TestTicketStoreConcurrentDoubleCommit(t *testing.T) {
+	const n, committers = 1000, 8
+	for _, c := range ticketStoreCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts := c.new()
+			for i := 0; i < n; i++ {
+				ticket := ts.Reserve()
+				var wg sync.WaitGroup
+				var mu sync.Mutex
+				succeeded := 0
+				for k := 0; k < committers; k++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						err := ts.Commit(ticket, ticket)
+						if err != nil && !errors.Is(err, ErrTicketCommitted) {
+							t.Error(err)
+						}
+						if err == nil {
+							mu.Lock()
+							succeeded++
+							mu.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+				if succeeded != 1 {
+					t.Fatalf("ticket %d committed %d times", ticket, succeeded)
+				}
+				if value, ok := ts.Next(); !ok || value != ticket {
+					t.Fatalf("next %d %v", value, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestTicketRingCommitConsumed(t *testing.T) {
+	const n, committers = 5000, 4
+	ts := NewRingTicketStore[uint64](1)
+	// latest is the last committed ticket plus one
+	var latest uint64
+	go func() {
+		for i := 0; i < n; i++ {
+			ticket := ts.Reserve()
+			if err := ts.Commit(ticket, ticket); err != nil {
+				t.Error(err)
+			}
+			atomic.StoreUint64(&latest, ticket+1)
+		}
+		ts.Close()
+	}()
+
+	// commit the latest ticket again and again while the consumer frees its
+	// slot for the next ticket
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for k := 0; k < committers; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ticket := atomic.LoadUint64(&latest)
+				if ticket == 0 {
+					runtime.Gosched()
+					continue
+				}
+				ticket--
+				if err := ts.Commit(ticket, n+ticket); !errors.Is(err, ErrTicketCommitted) {
+					t.Errorf("commit %d again: %v", ticket, err)
+					return
+				}
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	next := uint64(0)
+	for value := range ts.Chan(ctx) {
+		if value != next {
+			t.Fatalf("received %d, want %d", value, next)
+		}
+		next++
+	}
+	close(stop)
+	wg.Wait()
+	if next != n {
+		t.Errorf("received %d values, want %d", next, n)
+	}
+}
@@ -0,0 +1,276 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type trieNode[Value any] struct {
+	// prefix is the label of the edge from the parent node
+	prefix string
+	// children are sorted by the first byte of their prefix
+	children []*trieNode[Value]
+	value    Value
+	leaf     bool
+	// count is the number of keys in this subtree
+	count int
+}
+
+func (n *trieNode[Value]) child(b byte) (int, *trieNode[Value]) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].prefix[0] >= b })
+	if i < len(n.children) && n.children[i].prefix[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func (n *trieNode[Value]) insertChild(i int, c *trieNode[Value]) {
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+func (n *trieNode[Value]) removeChild(i int) {
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+}
+
+// walk visits the keys of the subtree in lexicographic byte order.
+func (n *trieNode[Value]) walk(key string, fn func(key string, value Value) bool) bool {
+	if n.leaf && !fn(key, n.value) {
+		return false
+	}
+	for _, c := range n.children {
+		if !c.walk(key+c.prefix, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Trie is a radix tree map from strings to values which supports queries by
+// key prefix. Keys are iterated in lexicographic byte order.
+type Trie[Value any] struct {
+	root trieNode[Value]
+}
+
+func NewTrie[Value any]() *Trie[Value] {
+	return &Trie[Value]{}
+}
+
+// Set stores value for key and reports whether key was new.
+func (t *Trie[Value]) Set(key string, value Value) bool {
+	path := []*trieNode[Value]{&t.root}
+	n, rest := &t.root, key
+	for rest != "" {
+		i, c := n.child(rest[0])
+		if c == nil {
+			n.insertChild(i, &trieNode[Value]{prefix: rest})
+			c = n.children[i]
+		} else if common := commonPrefix(c.prefix, rest); common < len(c.prefix) {
+			// split the edge at the end of the common prefix
+			mid := &trieNode[Value]{
+				prefix:   c.prefix[:common],
+				children: []*trieNode[Value]{c},
+				count:    c.count,
+			}
+			c.prefix = c.prefix[common:]
+			n.children[i] = mid
+			c = mid
+		}
+		rest = rest[len(c.prefix):]
+		n = c
+		path = append(path, n)
+	}
+
+	n.value = value
+	if n.leaf {
+		return false
+	}
+	n.leaf = true
+	for _, p := range path {
+		p.count++
+	}
+	return true
+}
+
+func (t *Trie[Value]) find(key string) (*trieNode[Value], []*trieNode[Value]) {
+	path := []*trieNode[Value]{&t.root}
+	n, rest := &t.root, key
+	for rest != "" {
+		_, c := n.child(rest[0])
+		if c == nil || !strings.HasPrefix(rest, c.prefix) {
+			return nil, nil
+		}
+		rest = rest[len(c.prefix):]
+		n = c
+		path = append(path, n)
+	}
+	return n, path
+}
+
+func (t *Trie[Value]) Get(key string) (Value, bool) {
+	if n, _ := t.find(key); n != nil && n.leaf {
+		return n.value, true
+	}
+	var zero Value
+	return zero, false
+}
+
+func (t *Trie[Value]) Has(key string) bool {
+	n, _ := t.find(key)
+	return n != nil && n.leaf
+}
+
+// Remove deletes key and reports whether it was present. Nodes left without
+// a purpose are removed or merged with their only child.
+func (t *Trie[Value]) Remove(key string) bool {
+	n, path := t.find(key)
+	if n == nil || !n.leaf {
+		return false
+	}
+
+	var zero Value
+	n.leaf, n.value = false, zero
+	for _, p := range path {
+		p.count--
+	}
+
+	for i := len(path) - 1; i > 0; i-- {
+		n, parent := path[i], path[i-1]
+		switch {
+		case n.leaf:
+			return true
+		case len(n.children) == 0:
+			j, _ := parent.child(n.prefix[0])
+			parent.removeChild(j)
+		case len(n.children) == 1:
+			c := n.children[0]
+			c.prefix = n.prefix + c.prefix
+			j, _ := parent.child(n.prefix[0])
+			parent.children[j] = c
+			return true
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+func (t *Trie[Value]) Len() int {
+	return t.root.count
+}
+
+// ForEach calls fn for every entry in key order until fn returns false.
+func (t *Trie[Value]) ForEach(fn func(key string, value Value) bool) {
+	t.root.walk("", fn)
+}
+
+func (t *Trie[Value]) Keys() []string {
+	keys := make([]string, 0, t.Len())
+	t.ForEach(func(key string, _ Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func (t *Trie[Value]) Values() []Value {
+	values := make([]Value, 0, t.Len())
+	t.ForEach(func(_ string, value Value) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// subtree returns the node whose subtree holds exactly the keys starting with
+// prefix together with the key of that node.
+func (t *Trie[Value]) subtree(prefix string) (*trieNode[Value], string) {
+	n, rest, key := &t.root, prefix, ""
+	for rest != "" {
+		_, c := n.child(rest[0])
+		switch {
+		case c == nil:
+			return nil, ""
+		case strings.HasPrefix(c.prefix, rest):
+			return c, key + c.prefix
+		case strings.HasPrefix(rest, c.prefix):
+			rest = rest[len(c.prefix):]
+			key += c.prefix
+			n = c
+		default:
+			return nil, ""
+		}
+	}
+	return n, key
+}
+
+// WalkPrefix calls fn for every entry whose key starts with prefix in key
+// order until fn returns false. Note that a directory prefix needs a trailing
+// separator to exclude siblings like "foobar" for "foo".
+func (t *Trie[Value]) WalkPrefix(prefix string, fn func(key string, value Value) bool) {
+	if n, key := t.subtree(prefix); n != nil {
+		n.walk(key, fn)
+	}
+}
+
+func (t *Trie[Value]) KeysWithPrefix(prefix string) []string {
+	keys := []string{}
+	t.WalkPrefix(prefix, func(key string, _ Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// CountPrefix returns the number of keys starting with prefix without
+// visiting them.
+func (t *Trie[Value]) CountPrefix(prefix string) int {
+	if n, _ := t.subtree(prefix); n != nil {
+		return n.count
+	}
+	return 0
+}
+
+// LongestPrefix returns the longest key which is a prefix of s.
+func (t *Trie[Value]) LongestPrefix(s string) (string, Value, bool) {
+	var value Value
+	found, length := t.root.leaf, 0
+	if found {
+		value = t.root.value
+	}
+
+	n, rest := &t.root, s
+	for rest != "" {
+		_, c := n.child(rest[0])
+		if c == nil || !strings.HasPrefix(rest, c.prefix) {
+			break
+		}
+		rest = rest[len(c.prefix):]
+		n = c
+		if n.leaf {
+			found, length, value = true, len(s)-len(rest), n.value
+		}
+	}
+	return s[:length], value, found
+}
+
+func (t *Trie[Value]) String() string {
+	values := []string{}
+	t.ForEach(func(key string, value Value) bool {
+		values = append(values, fmt.Sprintf("'%s':'%v'", key, value))
+		return true
+	})
+	return fmt.Sprintf("Trie[%s]", strings.Join(values, ", "))
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TrieStringSet is a StringSet backed by a radix tree. Besides the StringSet
+// operations it answers prefix queries, values are always sorted.
+type TrieStringSet struct {
+	trie Trie[void]
+}
+
+func NewTrieStringSet() *TrieStringSet {
+	return &TrieStringSet{}
+}
+
+func NewTrieStringSetWith(keys ...string) *TrieStringSet {
+	s := &TrieStringSet{}
+
+	for _, key := range keys {
+		s.Add(key)
+	}
+
+	return s
+}
+
+func (m *TrieStringSet) Remove(key string) {
+	m.trie.Remove(key)
+}
+
+func (m *TrieStringSet) Add(key string) {
+	m.trie.Set(key, empty)
+}
+
+func (m *TrieStringSet) Values() []string {
+	return m.trie.Keys()
+}
+
+func (m *TrieStringSet) SortedValues() []string {
+	return m.trie.Keys()
+}
+
+// ForEach calls fn for every value in order until fn returns false.
+func (m *TrieStringSet) ForEach(fn func(value string) bool) {
+	m.trie.ForEach(func(key string, _ void) bool {
+		return fn(key)
+	})
+}
+
+// WalkPrefix calls fn for every value starting with prefix in order until fn
+// returns false.
+func (m *TrieStringSet) WalkPrefix(prefix string, fn func(value string) bool) {
+	m.trie.WalkPrefix(prefix, func(key string, _ void) bool {
+		return fn(key)
+	})
+}
+
+func (m *TrieStringSet) ValuesWithPrefix(prefix string) []string {
+	return m.trie.KeysWithPrefix(prefix)
+}
+
+func (m *TrieStringSet) CountPrefix(prefix string) int {
+	return m.trie.CountPrefix(prefix)
+}
+
+// LongestPrefix returns the longest value which is a prefix of s.
+func (m *TrieStringSet) LongestPrefix(s string) (string, bool) {
+	prefix, _, ok := m.trie.LongestPrefix(s)
+	return prefix, ok
+}
+
+func (m *TrieStringSet) String() string {
+	return fmt.Sprintf("TrieStringSet[%s]", strings.Join(m.Values(), ", "))
+}
+
+func (m *TrieStringSet) Len() int {
+	return m.trie.Len()
+}
+
+func (m *TrieStringSet) Has(value string) bool {
+	return m.trie.Has(value)
+}